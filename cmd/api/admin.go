@@ -0,0 +1,292 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/sulavmhrzn/projectideas/internal/data"
+	"github.com/sulavmhrzn/projectideas/internal/i18n"
+	"github.com/sulavmhrzn/projectideas/internal/mailer"
+	"github.com/sulavmhrzn/projectideas/internal/validator"
+	"golang.org/x/term"
+)
+
+const adminUsage = `usage: api [flags] admin <command>
+
+commands:
+  users list                         list users
+  users create-admin <username> <email>
+                                     create an admin, reading the password from stdin
+  users grant-admin <email>          make a user an admin
+  users revoke-admin <email>         take admin rights away from a user
+  users ban <email>                  ban a user, end their sessions and email them
+  users unban <email>                lift a ban
+  users send-reset <email>           email a new password reset token
+  tokens purge                       delete expired tokens
+  tags merge <from> <into>           move the ideas and followers of a tag to another
+  check [-repair]                    report data integrity problems, and repair them
+  stats                              print counts of users, ideas, tags and tokens`
+
+// runAdmin carries out the admin subcommand with the arguments following it
+// on the command line.
+func (app *application) runAdmin(args []string) error {
+	db, err := openDB(app.cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	app.models = data.NewModel(db, app.cfg.dbQueryTimeout)
+	// Messages are only written to the outbox here; the running server
+	// delivers them.
+	app.mailQueue = mailer.NewQueue(&mailer.PostgresStore{DB: db, QueryTimeout: app.cfg.dbQueryTimeout}, nil, app.logger)
+
+	return app.admin(os.Stdout, args)
+}
+
+func (app *application) admin(out io.Writer, args []string) error {
+	ctx := context.Background()
+	if len(args) == 0 {
+		return errors.New(adminUsage)
+	}
+	command, args := strings.Join(args[:min(2, len(args))], " "), args[min(2, len(args)):]
+
+	switch {
+	case command == "users list":
+		users, err := app.models.User.List(ctx)
+		if err != nil {
+			return err
+		}
+		tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tUSERNAME\tEMAIL\tCREATED\tADMIN\tBANNED")
+		for _, u := range users {
+			banned := "-"
+			if u.Banned() {
+				banned = u.BannedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%t\t%s\n", u.Id, u.Username, u.Email, u.CreatedAt.Format(time.RFC3339), u.IsAdmin, banned)
+		}
+		return tw.Flush()
+	case command == "users create-admin" && len(args) == 2:
+		password, err := readPassword()
+		if err != nil {
+			return err
+		}
+		user, err := app.createAdmin(ctx, args[0], args[1], password)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "created admin %s with id %d\n", user.Username, user.Id)
+	case command == "users grant-admin" && len(args) == 1:
+		return app.updateUser(ctx, out, args[0], "granted admin rights to", func(u *data.User) error {
+			return app.models.User.SetAdmin(ctx, u.Id, true)
+		})
+	case command == "users revoke-admin" && len(args) == 1:
+		return app.updateUser(ctx, out, args[0], "revoked admin rights from", func(u *data.User) error {
+			return app.models.User.SetAdmin(ctx, u.Id, false)
+		})
+	case command == "users ban" && len(args) == 1:
+		return app.updateUser(ctx, out, args[0], "banned", func(u *data.User) error {
+			err := app.models.User.SetBanned(ctx, u.Id, true)
+			if err != nil {
+				return err
+			}
+			err = app.models.Token.DeleteForUser(ctx, u.Id)
+			if err != nil {
+				return err
+			}
+			return app.sendBannedEmail(ctx, u)
+		})
+	case command == "users unban" && len(args) == 1:
+		return app.updateUser(ctx, out, args[0], "unbanned", func(u *data.User) error {
+			err := app.models.User.SetBanned(ctx, u.Id, false)
+			if err != nil {
+				return err
+			}
+			return app.notifyModeration(ctx, u.Id)
+		})
+	case command == "users send-reset" && len(args) == 1:
+		return app.updateUser(ctx, out, args[0], "queued a password reset email for", func(u *data.User) error {
+			return app.sendResetPasswordEmail(ctx, u)
+		})
+	case command == "tokens purge" && len(args) == 0:
+		total, err := app.purgeExpiredTokens(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "deleted %d expired tokens\n", total)
+	case command == "tags merge" && len(args) == 2:
+		n, err := app.models.Admin.MergeTags(ctx, args[0], args[1])
+		if err != nil {
+			if errors.Is(err, data.ErrNoRows) {
+				return fmt.Errorf("no tag titled %q", args[0])
+			}
+			return err
+		}
+		fmt.Fprintf(out, "merged %q into %q, %d ideas retagged\n", args[0], args[1], n)
+	case command == "check" || command == "check -repair":
+		if len(args) != 0 {
+			return errors.New(adminUsage)
+		}
+		check := app.models.Admin.CheckIntegrity
+		if command == "check -repair" {
+			check = app.models.Admin.RepairIntegrity
+		}
+		report, err := check(ctx)
+		if err != nil {
+			return err
+		}
+		return printIntegrityReport(out, report, command == "check -repair")
+	case command == "stats":
+		if len(args) != 0 {
+			return errors.New(adminUsage)
+		}
+		stats, err := app.models.Admin.Stats(ctx)
+		if err != nil {
+			return err
+		}
+		return printStats(out, stats)
+	default:
+		return errors.New(adminUsage)
+	}
+	return nil
+}
+
+// createAdmin registers a user the same way the api does and makes them an
+// admin.
+func (app *application) createAdmin(ctx context.Context, username, email, password string) (*data.User, error) {
+	user := &data.User{Username: username, Email: email}
+	user.Password.PlainPassword = password
+
+	v := validator.New()
+	if data.ValidateUser(v, user); !v.Valid() {
+		keys := make([]string, 0, len(v.Errors))
+		for key := range v.Errors {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		var msgs []string
+		for _, key := range keys {
+			for _, e := range v.Errors[key] {
+				msgs = append(msgs, key+": "+i18n.Translate(i18n.Fallback, e.Code, e.Args...))
+			}
+		}
+		return nil, errors.New(strings.Join(msgs, "\n"))
+	}
+	err := user.Password.Set(password)
+	if err != nil {
+		return nil, err
+	}
+	// Inserted as an admin in one statement, so a failure leaves no plain
+	// account behind.
+	user.IsAdmin = true
+	return app.models.User.Insert(ctx, user)
+}
+
+// updateUser looks up the user with the given email, applies fn and reports
+// what was done.
+func (app *application) updateUser(ctx context.Context, out io.Writer, email, done string, fn func(*data.User) error) error {
+	user, err := app.models.User.GetByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, data.ErrNoRows) {
+			return fmt.Errorf("no user with email %q", email)
+		}
+		return err
+	}
+	err = fn(user)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "%s %s\n", done, user.Username)
+	return nil
+}
+
+// sendBannedEmail tells user that they were banned. It is sent by email since
+// a banned user cannot log in to read notifications.
+func (app *application) sendBannedEmail(ctx context.Context, user *data.User) error {
+	msg, err := mailer.Render("banned.tmpl", map[string]any{"Username": user.Username})
+	if err != nil {
+		return err
+	}
+	msg.From = app.cfg.mailer.EmailFrom
+	msg.To = user.Email
+	return app.mailQueue.Enqueue(ctx, msg)
+}
+
+// notifyModeration tells userId that an admin acted on their account. There
+// is no actor since admin commands are not run as a user.
+func (app *application) notifyModeration(ctx context.Context, userId int) error {
+	_, err := app.models.Notification.Insert(ctx, &data.Notification{
+		UserId: userId,
+		Event:  data.EventModeration,
+	})
+	return err
+}
+
+func printIntegrityReport(out io.Writer, report *data.IntegrityReport, repaired bool) error {
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "orphan ideas_tags rows\t%d\n", report.OrphanIdeaTags)
+	fmt.Fprintf(tw, "duplicate ideas_tags rows\t%d\n", report.DuplicateIdeaTags)
+	fmt.Fprintf(tw, "duplicate tags\t%d\n", report.DuplicateTags)
+	fmt.Fprintf(tw, "unused tags\t%d\n", report.UnusedTags)
+	fmt.Fprintf(tw, "ideas without tags\t%d\n", len(report.UntaggedIdeas))
+	err := tw.Flush()
+	if err != nil {
+		return err
+	}
+	if len(report.UntaggedIdeas) > 0 {
+		ids := make([]string, len(report.UntaggedIdeas))
+		for i, id := range report.UntaggedIdeas {
+			ids[i] = fmt.Sprint(id)
+		}
+		fmt.Fprintf(out, "untagged idea ids: %s\n", strings.Join(ids, ", "))
+	}
+	if repaired {
+		fmt.Fprintln(out, "repaired everything but ideas without tags")
+	}
+	return nil
+}
+
+func printStats(out io.Writer, stats *data.Stats) error {
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "users\t%d\n", stats.Users)
+	fmt.Fprintf(tw, "admins\t%d\n", stats.Admins)
+	fmt.Fprintf(tw, "banned users\t%d\n", stats.BannedUsers)
+	fmt.Fprintf(tw, "pending deletion\t%d\n", stats.PendingDeletion)
+	fmt.Fprintf(tw, "ideas\t%d\n", stats.Ideas)
+	fmt.Fprintf(tw, "tags\t%d\n", stats.Tags)
+	fmt.Fprintf(tw, "active tokens\t%d\n", stats.ActiveTokens)
+	fmt.Fprintf(tw, "expired tokens\t%d\n", stats.ExpiredTokens)
+	for i, tag := range stats.TopTags {
+		label := ""
+		if i == 0 {
+			label = "top tags"
+		}
+		fmt.Fprintf(tw, "%s\t%s (%d)\n", label, tag.Title, tag.Ideas)
+	}
+	return tw.Flush()
+}
+
+// readPassword reads a password from the terminal without echoing it, or a
+// line from stdin when it is not a terminal.
+func readPassword() (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return "", err
+		}
+		return strings.TrimRight(line, "\r\n"), nil
+	}
+	fmt.Fprint(os.Stderr, "Password: ")
+	b, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	return string(b), err
+}
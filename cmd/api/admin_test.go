@@ -0,0 +1,204 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sulavmhrzn/projectideas/internal/data"
+)
+
+func TestAdminBan(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.router())
+	token := ts.register(t, "alice", "alice@example.com")
+
+	var out bytes.Buffer
+	err := app.admin(&out, []string{"users", "ban", "alice@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := out.String(); got != "banned alice\n" {
+		t.Errorf("got output %q", got)
+	}
+	msg := waitForMail(t, app, 1)[0]
+	if msg.To != "alice@example.com" || msg.Subject != "Your account has been suspended" {
+		t.Errorf("got message to %q with subject %q, want the ban notice", msg.To, msg.Subject)
+	}
+
+	status, _ := ts.do(t, http.MethodGet, "/v1/feed", token, nil)
+	if status != http.StatusUnauthorized {
+		t.Errorf("token of a banned user: got status %d, want %d", status, http.StatusUnauthorized)
+	}
+	login := map[string]string{"email": "alice@example.com", "password": "pa55word1234"}
+	status, _ = ts.do(t, http.MethodPost, "/v1/tokens/authentication", "", login)
+	if status != http.StatusUnauthorized {
+		t.Errorf("login of a banned user: got status %d, want %d", status, http.StatusUnauthorized)
+	}
+
+	err = app.admin(&out, []string{"users", "unban", "alice@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	status, body := ts.do(t, http.MethodPost, "/v1/tokens/authentication", "", login)
+	if status != http.StatusOK {
+		t.Fatalf("login after unban: got status %d, want %d", status, http.StatusOK)
+	}
+	token = body["token"].(string)
+	status, body = ts.do(t, http.MethodGet, "/v1/notifications", token, nil)
+	if status != http.StatusOK {
+		t.Fatalf("list notifications: got status %d: %v", status, body)
+	}
+	notifications, _ := body["notifications"].([]any)
+	if len(notifications) != 1 {
+		t.Fatalf("got notifications %v, want one for the unban", body)
+	}
+	for _, n := range notifications {
+		if event := n.(map[string]any)["event"]; event != data.EventModeration {
+			t.Errorf("got event %v, want %q", event, data.EventModeration)
+		}
+	}
+
+	err = app.admin(&out, []string{"users", "ban", "nobody@example.com"})
+	if err == nil || !strings.Contains(err.Error(), "no user") {
+		t.Errorf("banning an unknown user: got error %v", err)
+	}
+}
+
+func TestAdminCreateAdmin(t *testing.T) {
+	app := newTestApplication(t)
+	ctx := context.Background()
+
+	_, err := app.createAdmin(ctx, "root", "root@example.com", "short")
+	if err == nil || !strings.Contains(err.Error(), "password:") {
+		t.Errorf("short password: got error %v", err)
+	}
+	user, err := app.createAdmin(ctx, "root", "root@example.com", "pa55word1234")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored, err := app.models.User.GetByEmail(ctx, "root@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !stored.IsAdmin || stored.Id != user.Id {
+		t.Errorf("got %+v, want admin with id %d", stored, user.Id)
+	}
+}
+
+func TestAdminSendReset(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.router())
+	ts.register(t, "alice", "alice@example.com")
+
+	var out bytes.Buffer
+	err := app.admin(&out, []string{"users", "send-reset", "alice@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := out.String(), "queued a password reset email for alice\n"; got != want {
+		t.Errorf("got output %q, want %q", got, want)
+	}
+	msg := waitForMail(t, app, 1)[0]
+	if msg.To != "alice@example.com" || !resetToken.MatchString(msg.PlainBody) {
+		t.Errorf("got message %+v, want a reset token for alice", msg)
+	}
+}
+
+func TestAdminTags(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.router())
+	token := ts.register(t, "alice", "alice@example.com")
+	for _, tags := range [][]string{{"golang"}, {"go", "golang"}, {"go"}, {"rust"}} {
+		var input []map[string]string
+		for _, tag := range tags {
+			input = append(input, map[string]string{"title": tag})
+		}
+		status, body := ts.do(t, http.MethodPost, "/v1/ideas", token, map[string]any{
+			"title":       "An idea",
+			"description": "Something to build",
+			"tags":        input,
+		})
+		if status != http.StatusCreated {
+			t.Fatalf("create idea: got status %d: %v", status, body)
+		}
+	}
+
+	var out bytes.Buffer
+	err := app.admin(&out, []string{"tags", "merge", "golang", "go"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := out.String(), "merged \"golang\" into \"go\", 1 ideas retagged\n"; got != want {
+		t.Errorf("got output %q, want %q", got, want)
+	}
+	err = app.admin(&out, []string{"tags", "merge", "golang", "go"})
+	if err == nil {
+		t.Error("merging a tag that no longer exists succeeded")
+	}
+
+	stats, err := app.models.Admin.Stats(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []data.TagCount{{Title: "go", Ideas: 3}, {Title: "rust", Ideas: 1}}
+	if len(stats.TopTags) != len(want) || stats.TopTags[0] != want[0] || stats.TopTags[1] != want[1] {
+		t.Errorf("got top tags %v, want %v", stats.TopTags, want)
+	}
+	if stats.Users != 1 || stats.Ideas != 4 || stats.Tags != 2 {
+		t.Errorf("got stats %+v", stats)
+	}
+
+	report, err := app.models.Admin.CheckIntegrity(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.DuplicateTags != 0 || report.UnusedTags != 0 || len(report.UntaggedIdeas) != 0 {
+		t.Errorf("got report %+v after merging", report)
+	}
+}
+
+func TestAdminPurgeTokens(t *testing.T) {
+	app := newTestApplication(t)
+	ctx := context.Background()
+	for i := 0; i < purgeBatch+5; i++ {
+		token := &data.Token{UserId: 1, Token: fmt.Sprintf("expired-%d", i), ExpiresAt: time.Now().Add(-time.Minute)}
+		err := app.models.Token.Insert(ctx, token)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, err := app.models.Token.New(ctx, 1, time.Hour, data.ScopeAuthentication)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := app.purgeExpiredTokens(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != purgeBatch+5 {
+		t.Errorf("deleted %d tokens, want %d", n, purgeBatch+5)
+	}
+	tokens, err := app.models.Token.ListForUser(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tokens) != 1 {
+		t.Errorf("%d tokens left, want 1", len(tokens))
+	}
+}
+
+func TestAdminUsage(t *testing.T) {
+	app := newTestApplication(t)
+	for _, args := range [][]string{nil, {"users"}, {"users", "ban"}, {"tags", "merge", "go"}, {"stats", "now"}} {
+		err := app.admin(&bytes.Buffer{}, args)
+		if err == nil || err.Error() != adminUsage {
+			t.Errorf("%q: got error %v, want usage", args, err)
+		}
+	}
+}
@@ -3,15 +3,34 @@ package main
 import (
 	"context"
 	"net/http"
+	"strconv"
 
 	"github.com/sulavmhrzn/projectideas/internal/data"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
 )
 
 type contextKey string
 
-var userContextKey = contextKey("user")
+var (
+	userContextKey            = contextKey("user")
+	requestMetadataContextKey = contextKey("requestMetadata")
+)
+
+// requestMetadata is shared by every handler and middleware serving a
+// request, so values set deep in the chain are visible to the access log.
+type requestMetadata struct {
+	id     string
+	route  string
+	userId int
+}
 
 func (app *application) contextSetUser(r *http.Request, user *data.User) *http.Request {
+	if !user.IsAnonymousUser() {
+		if meta := app.contextGetRequestMetadata(r); meta != nil {
+			meta.userId = user.Id
+		}
+		app.traceAttributes(r, semconv.EnduserID(strconv.Itoa(user.Id)))
+	}
 	ctx := context.WithValue(r.Context(), userContextKey, user)
 	return r.WithContext(ctx)
 }
@@ -23,3 +42,20 @@ func (app *application) contextGetUser(r *http.Request) *data.User {
 	}
 	return user
 }
+
+func (app *application) contextSetRequestMetadata(r *http.Request, meta *requestMetadata) *http.Request {
+	ctx := context.WithValue(r.Context(), requestMetadataContextKey, meta)
+	return r.WithContext(ctx)
+}
+
+func (app *application) contextGetRequestMetadata(r *http.Request) *requestMetadata {
+	meta, _ := r.Context().Value(requestMetadataContextKey).(*requestMetadata)
+	return meta
+}
+
+func (app *application) contextGetRequestID(r *http.Request) string {
+	if meta := app.contextGetRequestMetadata(r); meta != nil {
+		return meta.id
+	}
+	return ""
+}
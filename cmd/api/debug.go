@@ -0,0 +1,61 @@
+package main
+
+import (
+	"html/template"
+	"net/http"
+
+	"github.com/sulavmhrzn/projectideas/internal/mailer"
+)
+
+var mailPreviewTemplate = template.Must(template.New("mail").Parse(`<!doctype html>
+<html>
+<head>
+    <meta charset="utf-8">
+    <title>Sent mail</title>
+    <style>
+        body { font-family: sans-serif; margin: 2em; }
+        article { border: 1px solid #ccc; margin-bottom: 2em; padding: 1em; }
+        iframe { border: 1px solid #eee; width: 100%; height: 400px; }
+        pre { white-space: pre-wrap; background: #f6f6f6; padding: 1em; }
+    </style>
+</head>
+<body>
+    <h1>Sent mail ({{len .}})</h1>
+    {{range $i, $m := .}}
+    <article id="mail-{{$i}}">
+        <h2>{{$m.Subject}}</h2>
+        <p><strong>From:</strong> {{$m.From}}<br><strong>To:</strong> {{$m.To}}</p>
+        {{range $name, $value := $m.Headers}}<p><strong>{{$name}}:</strong> {{$value}}</p>{{end}}
+        {{if $m.HTMLBody}}<iframe sandbox srcdoc="{{$m.HTMLBody}}"></iframe>{{end}}
+        <pre>{{$m.PlainBody}}</pre>
+    </article>
+    {{else}}
+    <p>No mail has been sent yet.</p>
+    {{end}}
+</body>
+</html>
+`))
+
+// listMailHandler shows the email captured in development mode, newest first,
+// as an HTML page or as JSON when called with ?format=json.
+func (app *application) listMailHandler(w http.ResponseWriter, r *http.Request) {
+	sent := app.mailCatcher.Messages()
+	messages := make([]mailer.Message, 0, len(sent))
+	for i := len(sent) - 1; i >= 0; i-- {
+		messages = append(messages, sent[i])
+	}
+
+	if r.URL.Query().Get("format") == "json" {
+		err := app.writeJSON(w, http.StatusOK, map[string]any{"messages": messages})
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	err := mailPreviewTemplate.Execute(w, messages)
+	if err != nil {
+		app.logError(r, err)
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sulavmhrzn/projectideas/internal/data"
+	"github.com/sulavmhrzn/projectideas/internal/mailer"
+	"github.com/sulavmhrzn/projectideas/internal/validator"
+)
+
+// digestIdeasLimit caps how many ideas each section of a digest lists.
+const digestIdeasLimit = 10
+
+func (app *application) unsubscribeSignature(userId int) string {
+	mac := hmac.New(sha256.New, []byte(app.cfg.digestSecret))
+	fmt.Fprintf(mac, "digest-unsubscribe:%d", userId)
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// unsubscribeURL returns a link that turns off the digest of userId without
+// requiring the user to log in.
+func (app *application) unsubscribeURL(userId int) string {
+	token := fmt.Sprintf("%d.%s", userId, app.unsubscribeSignature(userId))
+	return fmt.Sprintf("%s/v1/users/digest/unsubscribe?token=%s", app.cfg.baseURL, url.QueryEscape(token))
+}
+
+// verifyUnsubscribeToken returns the user a token was issued for. Without a
+// secret anyone could sign tokens, so every token is rejected.
+func (app *application) verifyUnsubscribeToken(token string) (int, bool) {
+	if app.cfg.digestSecret == "" {
+		return 0, false
+	}
+	id, signature, found := strings.Cut(token, ".")
+	if !found {
+		return 0, false
+	}
+	userId, err := strconv.Atoi(id)
+	if err != nil {
+		return 0, false
+	}
+	expected := app.unsubscribeSignature(userId)
+	if !hmac.Equal([]byte(signature), []byte(expected)) {
+		return 0, false
+	}
+	return userId, true
+}
+
+func (app *application) sendDigestsJob() {
+	ctx := context.Background()
+	digests, err := app.models.Digest.Due(ctx)
+	if err != nil {
+		app.logJobError(err)
+		return
+	}
+	queued := 0
+	for i := range digests {
+		digest := &digests[i]
+		err := app.models.Digest.Fill(ctx, digest, digestIdeasLimit)
+		if err != nil {
+			app.logJobError(err)
+			continue
+		}
+		if !digest.Empty() {
+			unsubscribeURL := app.unsubscribeURL(digest.User.Id)
+			msg, err := mailer.Render("digest.tmpl", struct {
+				*data.Digest
+				BaseURL        string
+				UnsubscribeURL string
+			}{digest, app.cfg.baseURL, unsubscribeURL})
+			if err != nil {
+				app.logJobError(err)
+				continue
+			}
+			msg.From = app.cfg.mailer.EmailFrom
+			msg.To = digest.User.Email
+			msg.Headers = map[string]string{
+				"List-Unsubscribe":      fmt.Sprintf("<%s>", unsubscribeURL),
+				"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+			}
+			err = app.mailQueue.Enqueue(ctx, msg)
+			if err != nil {
+				app.logJobError(err)
+				continue
+			}
+			queued++
+		}
+		err = app.models.Digest.MarkSent(ctx, digest.User.Id, time.Now())
+		if err != nil {
+			app.logJobError(err)
+		}
+	}
+	if queued > 0 {
+		app.logger.Info("queued digest emails", "count", queued)
+	}
+}
+
+func (app *application) updateDigestFrequencyHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	var input struct {
+		Frequency string `json:"frequency"`
+	}
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	v := validator.New()
+	v.Check(validator.In(input.Frequency, data.DigestFrequencies...), "frequency", validator.NotPermitted, strings.Join(data.DigestFrequencies, ", "))
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Digest.SetFrequency(r.Context(), user.Id, input.Frequency)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, map[string]string{"frequency": input.Frequency})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// unsubscribeDigestHandler serves both the link in the email body and the
+// RFC 8058 one-click POST made by mail clients.
+func (app *application) unsubscribeDigestHandler(w http.ResponseWriter, r *http.Request) {
+	userId, ok := app.verifyUnsubscribeToken(r.URL.Query().Get("token"))
+	if !ok {
+		app.invalidTokenResponse(w, r)
+		return
+	}
+	err := app.models.Digest.SetFrequency(r.Context(), userId, data.DigestNever)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrNoRows):
+			app.invalidTokenResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, map[string]string{"message": "unsubscribed from digest emails"})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -1,57 +1,235 @@
 package main
 
 import (
+	"errors"
+	"fmt"
+	"mime"
 	"net/http"
 	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sulavmhrzn/projectideas/internal/data"
+	"github.com/sulavmhrzn/projectideas/internal/i18n"
+	"github.com/sulavmhrzn/projectideas/internal/validator"
+)
+
+// Error codes identify the kind of an error response. Clients match on them,
+// so they must not change once released.
+const (
+	codeServerError        = "server_error"
+	codeBadRequest         = "bad_request"
+	codeValidationFailed   = "validation_failed"
+	codeInvalidCredentials = "invalid_credentials"
+	codeInvalidToken       = "invalid_token"
+	codeUnauthorized       = "authentication_required"
+	codeNotFound           = "not_found"
+	codeMethodNotAllowed   = "method_not_allowed"
+	codeRateLimitExceeded  = "rate_limit_exceeded"
+)
+
+// Message codes of bad request errors, translated by the i18n catalogs.
+const (
+	msgBodyEmpty         = "body_empty"
+	msgBodyMalformed     = "body_malformed"
+	msgBodySyntax        = "body_syntax"
+	msgBodyFieldType     = "body_field_type"
+	msgBodyUnknownField  = "body_unknown_field"
+	msgBodyTooLarge      = "body_too_large"
+	msgBodyTrailingData  = "body_trailing_data"
+	msgDuplicateEmail    = "duplicate_email"
+	msgDuplicateUsername = "duplicate_username"
+	msgCannotFollowSelf  = "cannot_follow_self"
 )
 
-func (app *application) logError(err error) {
+// requestError is a problem with a request, reported to the client in its
+// language.
+type requestError struct {
+	code string
+	args []any
+}
+
+func (e *requestError) Error() string {
+	return i18n.Translate(i18n.Fallback, e.code, e.args...)
+}
+
+// problem is an RFC 7807 problem details object, extended with the error code
+// and the fields that failed validation.
+type problem struct {
+	Type     string       `json:"type"`
+	Title    string       `json:"title"`
+	Status   int          `json:"status"`
+	Detail   string       `json:"detail,omitempty"`
+	Instance string       `json:"instance,omitempty"`
+	Code     string       `json:"code"`
+	Errors   []fieldError `json:"errors,omitempty"`
+}
+
+type fieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// logError logs err along with where it was reported from, which is the
+// caller of the helper calling logError. r is nil for errors raised outside of
+// a request; background jobs use logJobError.
+func (app *application) logError(r *http.Request, err error) {
 	_, file, line, _ := runtime.Caller(2)
-	app.errorLog.Printf("%s:%d %v", file, line, err)
+	attrs := []any{"source", fmt.Sprintf("%s:%d", file, line)}
+	if r != nil {
+		attrs = append(attrs, "request_id", app.contextGetRequestID(r), "method", r.Method, "uri", r.URL.RequestURI())
+		if id := traceID(r); id != "" {
+			attrs = append(attrs, "trace_id", id)
+		}
+	}
+	app.logger.Error(err.Error(), attrs...)
+}
+
+// logJobError logs an error raised by a background job, with the job as its
+// source.
+func (app *application) logJobError(err error) {
+	app.logError(nil, err)
 }
 
-func (app *application) errorResponse(w http.ResponseWriter, r *http.Request, status int, message any) {
-	data := map[string]any{
-		"error": message,
+// errorResponse sends message, either a string or validation errors keyed by
+// field, as application/problem+json when the client accepts it and as
+// {"error": message} otherwise. Validation errors and the title are
+// translated into the language negotiated from Accept-Language.
+func (app *application) errorResponse(w http.ResponseWriter, r *http.Request, status int, code string, message any) {
+	lang := app.language(r)
+	w.Header().Add("Vary", "Accept-Language")
+	w.Header().Set("Content-Language", lang)
+
+	var fields map[string][]string
+	if errs, ok := message.(map[string][]validator.Error); ok {
+		fields = translateErrors(lang, errs)
+		message = fields
+	}
+
+	var err error
+	if acceptsProblem(r) {
+		p := problem{
+			Type:     "/problems/" + code,
+			Title:    i18n.Translate(lang, code+".title"),
+			Status:   status,
+			Instance: app.contextGetRequestID(r),
+			Code:     code,
+		}
+		if fields != nil {
+			p.Detail = i18n.Translate(lang, "invalid_fields")
+			p.Errors = fieldErrors(fields)
+		} else {
+			p.Detail = fmt.Sprint(message)
+		}
+		err = app.writeJSONAs(w, status, "application/problem+json", p)
+	} else {
+		err = app.writeJSON(w, status, map[string]any{"error": message})
 	}
-	err := app.writeJSON(w, status, data)
 	if err != nil {
-		app.logError(err)
+		app.logError(r, err)
 		http.Error(w, "", http.StatusInternalServerError)
 	}
+}
+
+// language returns the language of the best matching message catalog.
+func (app *application) language(r *http.Request) string {
+	return i18n.Negotiate(r.Header.Get("Accept-Language"))
+}
 
+func translateErrors(lang string, errs map[string][]validator.Error) map[string][]string {
+	messages := make(map[string][]string, len(errs))
+	for field, fieldErrs := range errs {
+		for _, e := range fieldErrs {
+			messages[field] = append(messages[field], i18n.Translate(lang, e.Code, e.Args...))
+		}
+	}
+	return messages
+}
+
+// acceptsProblem reports whether the Accept header lists
+// application/problem+json with a quality above zero.
+func acceptsProblem(r *http.Request) bool {
+	for _, value := range r.Header.Values("Accept") {
+		for _, mediaRange := range strings.Split(value, ",") {
+			mediaType, params, err := mime.ParseMediaType(mediaRange)
+			if err != nil || mediaType != "application/problem+json" {
+				continue
+			}
+			q := 1.0
+			if value, ok := params["q"]; ok {
+				q, err = strconv.ParseFloat(value, 64)
+				if err != nil {
+					continue
+				}
+			}
+			if q > 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func fieldErrors(errs map[string][]string) []fieldError {
+	var fields []fieldError
+	for field, messages := range errs {
+		for _, message := range messages {
+			fields = append(fields, fieldError{Field: field, Message: message})
+		}
+	}
+	sort.SliceStable(fields, func(i, j int) bool { return fields[i].Field < fields[j].Field })
+	return fields
 }
 
 func (app *application) serverErrorResponse(w http.ResponseWriter, r *http.Request, err error) {
-	message := "internal server error"
-	app.logError(err)
-	app.errorResponse(w, r, http.StatusInternalServerError, message)
+	app.logError(r, err)
+	app.errorResponse(w, r, http.StatusInternalServerError, codeServerError, i18n.Translate(app.language(r), codeServerError))
 }
 
-func (app *application) badRequestResponse(w http.ResponseWriter, r *http.Request, message error) {
-	app.errorResponse(w, r, http.StatusBadRequest, message.Error())
+// badRequestResponse translates err when it is a requestError or a known
+// data error.
+func (app *application) badRequestResponse(w http.ResponseWriter, r *http.Request, err error) {
+	var reqErr *requestError
+	switch {
+	case errors.As(err, &reqErr):
+	case errors.Is(err, data.ErrDuplicateEmail):
+		reqErr = &requestError{code: msgDuplicateEmail}
+	case errors.Is(err, data.ErrDuplicateUsername):
+		reqErr = &requestError{code: msgDuplicateUsername}
+	default:
+		app.errorResponse(w, r, http.StatusBadRequest, codeBadRequest, err.Error())
+		return
+	}
+	message := i18n.Translate(app.language(r), reqErr.code, reqErr.args...)
+	app.errorResponse(w, r, http.StatusBadRequest, codeBadRequest, message)
 }
 
-func (app *application) failedValidationResponse(w http.ResponseWriter, r *http.Request, err any) {
-	app.errorResponse(w, r, http.StatusBadRequest, err)
+func (app *application) failedValidationResponse(w http.ResponseWriter, r *http.Request, errs map[string][]validator.Error) {
+	app.errorResponse(w, r, http.StatusBadRequest, codeValidationFailed, errs)
 }
 
 func (app *application) invalidCredentialsResponse(w http.ResponseWriter, r *http.Request) {
-	message := "invalid credentials"
-	app.errorResponse(w, r, http.StatusUnauthorized, message)
+	app.errorResponse(w, r, http.StatusUnauthorized, codeInvalidCredentials, i18n.Translate(app.language(r), codeInvalidCredentials))
 }
 
 func (app *application) invalidTokenResponse(w http.ResponseWriter, r *http.Request) {
-	message := "invalid token"
-	app.errorResponse(w, r, http.StatusUnauthorized, message)
+	app.errorResponse(w, r, http.StatusUnauthorized, codeInvalidToken, i18n.Translate(app.language(r), codeInvalidToken))
 }
 
 func (app *application) unauthorizedResponse(w http.ResponseWriter, r *http.Request) {
-	message := "you are not authorized to access this resource"
-	app.errorResponse(w, r, http.StatusUnauthorized, message)
+	app.errorResponse(w, r, http.StatusUnauthorized, codeUnauthorized, i18n.Translate(app.language(r), codeUnauthorized))
 }
 
 func (app *application) notFoundResponse(w http.ResponseWriter, r *http.Request) {
-	message := "resource not found"
-	app.errorResponse(w, r, http.StatusNotFound, message)
+	app.errorResponse(w, r, http.StatusNotFound, codeNotFound, i18n.Translate(app.language(r), codeNotFound))
+}
+
+func (app *application) methodNotAllowedResponse(w http.ResponseWriter, r *http.Request) {
+	message := i18n.Translate(app.language(r), codeMethodNotAllowed, r.Method)
+	app.errorResponse(w, r, http.StatusMethodNotAllowed, codeMethodNotAllowed, message)
+}
+
+func (app *application) rateLimitExceededResponse(w http.ResponseWriter, r *http.Request) {
+	app.errorResponse(w, r, http.StatusTooManyRequests, codeRateLimitExceeded, i18n.Translate(app.language(r), codeRateLimitExceeded))
 }
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/sulavmhrzn/projectideas/internal/data"
+	"github.com/sulavmhrzn/projectideas/internal/validator"
+)
+
+func (app *application) showProfileHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	profile, err := app.models.Follow.Profile(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrNoRows):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, map[string]any{"profile": profile})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) listFollowersHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	followers, err := app.models.Follow.Followers(r.Context(), id)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, map[string]any{"followers": followers})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) listFollowingHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	users, err := app.models.Follow.Following(r.Context(), id)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	tags, err := app.models.Follow.FollowedTags(r.Context(), id)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, map[string]any{"users": users, "tags": tags})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) followUserHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	if id == user.Id {
+		app.badRequestResponse(w, r, &requestError{code: msgCannotFollowSelf})
+		return
+	}
+	followed, err := app.models.Follow.FollowUser(r.Context(), user.Id, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrNoRows):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	if followed {
+		app.notify(r, id, data.EventFollow, user.Id, 0)
+	}
+	err = app.writeJSON(w, http.StatusOK, map[string]string{"message": "followed"})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) unfollowUserHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	err = app.models.Follow.UnfollowUser(r.Context(), user.Id, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrNoRows):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, map[string]string{"message": "unfollowed"})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) followTagHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+	title := app.readStringParam(r, "title")
+	err := app.models.Follow.FollowTag(r.Context(), user.Id, title)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrNoRows):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, map[string]string{"message": "followed"})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) unfollowTagHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+	title := app.readStringParam(r, "title")
+	err := app.models.Follow.UnfollowTag(r.Context(), user.Id, title)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrNoRows):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, map[string]string{"message": "unfollowed"})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) feedHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+	qs := r.URL.Query()
+
+	v := validator.New()
+	limit := app.readInt(qs, "limit", 20, v)
+	v.Check(validator.Between(limit, 1, 100), "limit", validator.OutOfRange, 1, 100)
+
+	var cursor *data.Cursor
+	if s := app.readString(qs, "cursor", ""); s != "" {
+		var err error
+		cursor, err = data.DecodeCursor(s)
+		v.Check(err == nil, "cursor", validator.InvalidCursor)
+	}
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	ideas, err := app.models.Idea.Feed(r.Context(), user.Id, cursor, limit)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	var nextCursor string
+	if len(ideas) == limit {
+		last := ideas[len(ideas)-1]
+		nextCursor = data.Cursor{CreatedAt: last.CreatedAt, Id: last.Id}.Encode()
+	}
+	err = app.writeJSON(w, http.StatusOK, map[string]any{"ideas": ideas, "next_cursor": nextCursor})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+// userId returns the id of the user registered with email.
+func userId(t *testing.T, app *application, email string) int {
+	t.Helper()
+
+	user, err := app.models.User.GetByEmail(context.Background(), email)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return user.Id
+}
+
+// titles returns the title of each idea in ideas.
+func titles(ideas any) []any {
+	var titles []any
+	for _, idea := range ideas.([]any) {
+		titles = append(titles, idea.(map[string]any)["title"])
+	}
+	return titles
+}
+
+func TestFollows(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.router())
+	alice := ts.register(t, "alice", "alice@example.com")
+	bob := ts.register(t, "bob", "bob@example.com")
+	carol := ts.register(t, "carol", "carol@example.com")
+	aliceId, bobId := userId(t, app, "alice@example.com"), userId(t, app, "bob@example.com")
+
+	for _, idea := range []struct {
+		token, title, tag string
+	}{
+		{bob, "Bob's idea", "go"},
+		{carol, "Carol's go idea", "go"},
+		{carol, "Carol's rust idea", "rust"},
+	} {
+		status, body := ts.do(t, http.MethodPost, "/v1/ideas", idea.token, map[string]any{
+			"title":       idea.title,
+			"description": "Something",
+			"tags":        []map[string]string{{"title": idea.tag}},
+		})
+		if status != http.StatusCreated {
+			t.Fatalf("create %q: got status %d: %v", idea.title, status, body)
+		}
+	}
+
+	status, body := ts.do(t, http.MethodGet, "/v1/feed", alice, nil)
+	if status != http.StatusOK || len(body["ideas"].([]any)) != 0 {
+		t.Errorf("feed without follows: got status %d: %v", status, body)
+	}
+
+	bobFollow := fmt.Sprintf("/v1/profiles/%d/follow", bobId)
+	for i := 0; i < 2; i++ {
+		status, body = ts.do(t, http.MethodPost, bobFollow, alice, nil)
+		if status != http.StatusOK {
+			t.Fatalf("follow bob: got status %d: %v", status, body)
+		}
+	}
+	status, body = ts.do(t, http.MethodPost, "/v1/tags/go/follow", alice, nil)
+	if status != http.StatusOK {
+		t.Fatalf("follow tag: got status %d: %v", status, body)
+	}
+	status, _ = ts.do(t, http.MethodPost, "/v1/tags/missing/follow", alice, nil)
+	if status != http.StatusNotFound {
+		t.Errorf("follow a missing tag: got status %d, want %d", status, http.StatusNotFound)
+	}
+	status, _ = ts.do(t, http.MethodPost, "/v1/profiles/999/follow", alice, nil)
+	if status != http.StatusNotFound {
+		t.Errorf("follow a missing user: got status %d, want %d", status, http.StatusNotFound)
+	}
+
+	status, body = ts.do(t, http.MethodGet, "/v1/feed", alice, nil)
+	if status != http.StatusOK {
+		t.Fatalf("feed: got status %d: %v", status, body)
+	}
+	if got, want := fmt.Sprint(titles(body["ideas"])), "[Carol's go idea Bob's idea]"; got != want {
+		t.Errorf("feed: got %s, want %s", got, want)
+	}
+	status, body = ts.do(t, http.MethodGet, "/v1/feed?limit=1", alice, nil)
+	if status != http.StatusOK || len(body["ideas"].([]any)) != 1 || body["next_cursor"] == "" {
+		t.Fatalf("first page: got status %d: %v", status, body)
+	}
+	status, body = ts.do(t, http.MethodGet, fmt.Sprintf("/v1/feed?limit=1&cursor=%s", body["next_cursor"]), alice, nil)
+	if got, want := fmt.Sprint(titles(body["ideas"])), "[Bob's idea]"; status != http.StatusOK || got != want {
+		t.Errorf("second page: got status %d and %s, want %s", status, got, want)
+	}
+
+	status, body = ts.do(t, http.MethodGet, fmt.Sprintf("/v1/profiles/%d/followers", bobId), "", nil)
+	if status != http.StatusOK {
+		t.Fatalf("followers: got status %d: %v", status, body)
+	}
+	if followers := body["followers"].([]any); len(followers) != 1 || followers[0].(map[string]any)["username"] != "alice" {
+		t.Errorf("followers: got %v, want alice", followers)
+	}
+	status, body = ts.do(t, http.MethodGet, fmt.Sprintf("/v1/profiles/%d/following", aliceId), "", nil)
+	if status != http.StatusOK {
+		t.Fatalf("following: got status %d: %v", status, body)
+	}
+	users, tags := body["users"].([]any), body["tags"].([]any)
+	if len(users) != 1 || users[0].(map[string]any)["username"] != "bob" || len(tags) != 1 || tags[0].(map[string]any)["title"] != "go" {
+		t.Errorf("following: got users %v and tags %v", users, tags)
+	}
+	status, body = ts.do(t, http.MethodGet, fmt.Sprintf("/v1/profiles/%d", bobId), "", nil)
+	if profile := body["profile"].(map[string]any); status != http.StatusOK || profile["followers"] != 1.0 {
+		t.Errorf("profile: got status %d: %v", status, body)
+	}
+
+	for _, path := range []string{bobFollow, "/v1/tags/go/follow"} {
+		status, body = ts.do(t, http.MethodDelete, path, alice, nil)
+		if status != http.StatusOK {
+			t.Fatalf("unfollow %s: got status %d: %v", path, status, body)
+		}
+	}
+	status, body = ts.do(t, http.MethodGet, "/v1/feed", alice, nil)
+	if status != http.StatusOK || len(body["ideas"].([]any)) != 0 {
+		t.Errorf("feed after unfollowing: got status %d: %v", status, body)
+	}
+	status, body = ts.do(t, http.MethodGet, fmt.Sprintf("/v1/profiles/%d/followers", bobId), "", nil)
+	if status != http.StatusOK || len(body["followers"].([]any)) != 0 {
+		t.Errorf("followers after unfollowing: got status %d: %v", status, body)
+	}
+}
@@ -0,0 +1,916 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sulavmhrzn/projectideas/internal/data"
+	"github.com/sulavmhrzn/projectideas/internal/ratelimit"
+)
+
+func TestRegisterUser(t *testing.T) {
+	ts := newTestServer(t, newTestApplication(t).router())
+
+	tests := []struct {
+		name     string
+		body     map[string]string
+		wantCode int
+	}{
+		{"valid", map[string]string{"username": "alice", "email": "alice@example.com", "password": "pa55word1234"}, http.StatusOK},
+		{"duplicate email", map[string]string{"username": "alice2", "email": "alice@example.com", "password": "pa55word1234"}, http.StatusBadRequest},
+		{"duplicate username", map[string]string{"username": "alice", "email": "other@example.com", "password": "pa55word1234"}, http.StatusBadRequest},
+		{"invalid email", map[string]string{"username": "bob", "email": "bob", "password": "pa55word1234"}, http.StatusBadRequest},
+		{"short password", map[string]string{"username": "bob", "email": "bob@example.com", "password": "short"}, http.StatusBadRequest},
+		{"missing username", map[string]string{"email": "bob@example.com", "password": "pa55word1234"}, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := ts.do(t, http.MethodPost, "/v1/users/register", "", tt.body)
+			if status != tt.wantCode {
+				t.Fatalf("got status %d, want %d: %v", status, tt.wantCode, body)
+			}
+			if status == http.StatusOK {
+				user := body["user"].(map[string]any)
+				if user["username"] != tt.body["username"] {
+					t.Errorf("got username %v, want %q", user["username"], tt.body["username"])
+				}
+				if _, ok := user["password"]; ok {
+					t.Error("response exposes the password")
+				}
+			}
+		})
+	}
+}
+
+func TestLogin(t *testing.T) {
+	ts := newTestServer(t, newTestApplication(t).router())
+	ts.register(t, "alice", "alice@example.com")
+
+	tests := []struct {
+		name     string
+		email    string
+		password string
+		wantCode int
+	}{
+		{"valid", "alice@example.com", "pa55word1234", http.StatusOK},
+		{"wrong password", "alice@example.com", "wrongpassword", http.StatusUnauthorized},
+		{"unknown email", "nobody@example.com", "pa55word1234", http.StatusUnauthorized},
+		{"invalid email", "alice", "pa55word1234", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := ts.do(t, http.MethodPost, "/v1/tokens/authentication", "", map[string]string{
+				"email":    tt.email,
+				"password": tt.password,
+			})
+			if status != tt.wantCode {
+				t.Fatalf("got status %d, want %d: %v", status, tt.wantCode, body)
+			}
+			if status == http.StatusOK && body["token"] == "" {
+				t.Error("response has no token")
+			}
+		})
+	}
+}
+
+// resetToken extracts the password reset token from a reset email.
+var resetToken = regexp.MustCompile(`"token": "([^"]+)"`)
+
+func TestResetPassword(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.router())
+	ts.register(t, "alice", "alice@example.com")
+
+	status, _ := ts.do(t, http.MethodPost, "/v1/users/sendResetPassword", "", map[string]string{"email": "bob@example.com"})
+	if status != http.StatusNotFound {
+		t.Errorf("unknown email: got status %d, want %d", status, http.StatusNotFound)
+	}
+	status, body := ts.do(t, http.MethodPost, "/v1/users/sendResetPassword", "", map[string]string{"email": "alice@example.com"})
+	if status != http.StatusOK {
+		t.Fatalf("send reset token: got status %d: %v", status, body)
+	}
+
+	msg := waitForMail(t, app, 1)[0]
+	if msg.To != "alice@example.com" || msg.Subject != "Reset your password" {
+		t.Errorf("got message to %q with subject %q", msg.To, msg.Subject)
+	}
+	match := resetToken.FindStringSubmatch(msg.PlainBody)
+	if match == nil {
+		t.Fatalf("no token in %q", msg.PlainBody)
+	}
+	if !strings.Contains(msg.HTMLBody, match[1]) {
+		t.Errorf("HTML body does not contain the token: %q", msg.HTMLBody)
+	}
+
+	reset := map[string]string{"token": match[1], "password": "short"}
+	status, _ = ts.do(t, http.MethodPut, "/v1/users/resetPassword", "", reset)
+	if status != http.StatusBadRequest {
+		t.Errorf("short password: got status %d, want %d", status, http.StatusBadRequest)
+	}
+	reset["password"] = "n3w pa55word"
+	status, body = ts.do(t, http.MethodPut, "/v1/users/resetPassword", "", reset)
+	if status != http.StatusOK {
+		t.Fatalf("reset password: got status %d: %v", status, body)
+	}
+	status, _ = ts.do(t, http.MethodPut, "/v1/users/resetPassword", "", reset)
+	if status != http.StatusUnauthorized {
+		t.Errorf("reusing the token: got status %d, want %d", status, http.StatusUnauthorized)
+	}
+
+	for password, want := range map[string]int{"pa55word1234": http.StatusUnauthorized, "n3w pa55word": http.StatusOK} {
+		status, _ = ts.do(t, http.MethodPost, "/v1/tokens/authentication", "", map[string]string{"email": "alice@example.com", "password": password})
+		if status != want {
+			t.Errorf("login with %q: got status %d, want %d", password, status, want)
+		}
+	}
+}
+
+func TestIdeaCRUD(t *testing.T) {
+	ts := newTestServer(t, newTestApplication(t).router())
+	token := ts.register(t, "alice", "alice@example.com")
+
+	status, body := ts.do(t, http.MethodPost, "/v1/ideas", token, map[string]any{
+		"title":       "Habit tracker",
+		"description": "Track daily habits",
+		"tags":        []map[string]string{{"title": "go"}, {"title": "cli"}},
+	})
+	if status != http.StatusCreated {
+		t.Fatalf("create: got status %d: %v", status, body)
+	}
+	path := fmt.Sprintf("/v1/ideas/%v", body["id"])
+
+	status, body = ts.do(t, http.MethodGet, path, "", nil)
+	if status != http.StatusOK {
+		t.Fatalf("get: got status %d: %v", status, body)
+	}
+	if body["title"] != "Habit tracker" || len(body["tags"].([]any)) != 2 {
+		t.Errorf("get: unexpected idea %v", body)
+	}
+
+	status, _ = ts.do(t, http.MethodGet, "/v1/ideas", "", nil)
+	if status != http.StatusOK {
+		t.Fatalf("list: got status %d", status)
+	}
+
+	status, body = ts.do(t, http.MethodPut, path, token, map[string]string{"title": "Mood tracker"})
+	if status != http.StatusOK {
+		t.Fatalf("update: got status %d: %v", status, body)
+	}
+	status, body = ts.do(t, http.MethodGet, path, "", nil)
+	if status != http.StatusOK || body["title"] != "Mood tracker" || body["description"] != "Track daily habits" {
+		t.Errorf("get after update: got status %d: %v", status, body)
+	}
+
+	status, body = ts.do(t, http.MethodDelete, path, token, nil)
+	if status != http.StatusOK {
+		t.Fatalf("delete: got status %d: %v", status, body)
+	}
+	status, _ = ts.do(t, http.MethodGet, path, "", nil)
+	if status != http.StatusNotFound {
+		t.Errorf("get after delete: got status %d, want %d", status, http.StatusNotFound)
+	}
+}
+
+func TestCreateIdeaValidation(t *testing.T) {
+	ts := newTestServer(t, newTestApplication(t).router())
+	token := ts.register(t, "alice", "alice@example.com")
+
+	tests := []struct {
+		name    string
+		body    map[string]any
+		wantKey string
+	}{
+		{"missing title", map[string]any{"description": "d", "tags": []map[string]string{{"title": "go"}}}, "title"},
+		{"missing tags", map[string]any{"title": "t", "description": "d"}, "tags"},
+		{"blank tag title", map[string]any{"title": "t", "description": "d", "tags": []map[string]string{{"title": "go"}, {"title": ""}}}, "tags[1].title"},
+		{"duplicate tags", map[string]any{"title": "t", "description": "d", "tags": []map[string]string{{"title": "go"}, {"title": "go"}}}, "tags"},
+		{"long title", map[string]any{"title": strings.Repeat("é", 100), "description": "d", "tags": []map[string]string{{"title": "go"}}}, "title"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := ts.do(t, http.MethodPost, "/v1/ideas", token, tt.body)
+			if status != http.StatusBadRequest {
+				t.Fatalf("got status %d, want %d: %v", status, http.StatusBadRequest, body)
+			}
+			errs, _ := body["error"].(map[string]any)
+			if _, ok := errs[tt.wantKey]; !ok {
+				t.Errorf("no error for %q: %v", tt.wantKey, body)
+			}
+		})
+	}
+}
+
+func TestAuthFailures(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.router())
+	alice := ts.register(t, "alice", "alice@example.com")
+	bob := ts.register(t, "bob", "bob@example.com")
+
+	_, body := ts.do(t, http.MethodPost, "/v1/ideas", alice, map[string]any{
+		"title":       "Habit tracker",
+		"description": "Track daily habits",
+		"tags":        []map[string]string{{"title": "go"}},
+	})
+	path := fmt.Sprintf("/v1/ideas/%v", body["id"])
+	idea := map[string]any{
+		"title":       "Recipe box",
+		"description": "Store recipes",
+		"tags":        []map[string]string{{"title": "web"}},
+	}
+
+	t.Run("missing token", func(t *testing.T) {
+		status, _ := ts.do(t, http.MethodPost, "/v1/ideas", "", idea)
+		if status != http.StatusUnauthorized {
+			t.Errorf("got status %d, want %d", status, http.StatusUnauthorized)
+		}
+	})
+
+	t.Run("malformed header", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodDelete, ts.URL+path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Token "+alice)
+		res, err := ts.Client().Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		if res.StatusCode != http.StatusUnauthorized {
+			t.Errorf("got status %d, want %d", res.StatusCode, http.StatusUnauthorized)
+		}
+	})
+
+	t.Run("unknown token", func(t *testing.T) {
+		status, _ := ts.do(t, http.MethodPost, "/v1/ideas", "ABCDEFGHIJKLMNOPQRSTUVWXYZ", idea)
+		if status != http.StatusUnauthorized {
+			t.Errorf("got status %d, want %d", status, http.StatusUnauthorized)
+		}
+	})
+
+	t.Run("other user's idea", func(t *testing.T) {
+		status, _ := ts.do(t, http.MethodDelete, path, bob, nil)
+		if status != http.StatusNotFound {
+			t.Errorf("delete: got status %d, want %d", status, http.StatusNotFound)
+		}
+		status, _ = ts.do(t, http.MethodPut, path, bob, map[string]string{"title": "Stolen"})
+		if status != http.StatusNotFound {
+			t.Errorf("update: got status %d, want %d", status, http.StatusNotFound)
+		}
+		status, body := ts.do(t, http.MethodGet, path, "", nil)
+		if status != http.StatusOK || body["title"] != "Habit tracker" {
+			t.Errorf("idea changed by another user: %v", body)
+		}
+	})
+}
+
+func TestRequestBodyLimits(t *testing.T) {
+	ts := newTestServer(t, newTestApplication(t).router())
+
+	tests := []struct {
+		name    string
+		body    string
+		wantErr string
+	}{
+		{"trailing data", `{"email": "alice@example.com", "password": "pa55word1234"} {}`, "body must only contain a single JSON value"},
+		{"too large", `{"email": "` + strings.Repeat("a", smallBodyBytes) + `@example.com", "password": "pa55word1234"}`, fmt.Sprintf("body must not be larger than %d bytes", smallBodyBytes)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := ts.doRaw(t, http.MethodPost, "/v1/tokens/authentication", "", []byte(tt.body))
+			if status != http.StatusBadRequest {
+				t.Fatalf("got status %d, want %d", status, http.StatusBadRequest)
+			}
+			if body["error"] != tt.wantErr {
+				t.Errorf("got error %v, want %q", body["error"], tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRecoverPanic(t *testing.T) {
+	app := newTestApplication(t)
+	h := app.requestIDMiddleware(app.recoverPanic(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})))
+	ts := newTestServer(t, h)
+
+	req, err := http.NewRequest(http.MethodGet, ts.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusInternalServerError {
+		t.Errorf("got status %d, want %d", res.StatusCode, http.StatusInternalServerError)
+	}
+	if !res.Close {
+		t.Error("connection was not closed")
+	}
+	var body map[string]any
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if body["error"] != "internal server error" {
+		t.Errorf("got error %v, want %q", body["error"], "internal server error")
+	}
+}
+
+func TestRecoverMiddlewarePanic(t *testing.T) {
+	app := newTestApplication(t)
+	var logs bytes.Buffer
+	app.logger = slog.New(slog.NewTextHandler(&logs, nil))
+	ts := newTestServer(t, app.router())
+	// metricsMiddleware panics once the handler has returned.
+	app.metrics = &metrics{}
+
+	// The response was already sent, so the panic is only logged.
+	status, body := ts.do(t, http.MethodGet, "/v1/ping", "", nil)
+	if status != http.StatusOK || body["error"] != nil {
+		t.Errorf("got status %d and body %v, want the ping response", status, body)
+	}
+	if !strings.Contains(logs.String(), "panic: runtime error") {
+		t.Errorf("panic was not logged: %s", logs.String())
+	}
+}
+
+func TestLongestLimitPeriod(t *testing.T) {
+	var cfg config
+	cfg.limiter.defaultLimit = ratelimit.Limit{Rate: 2, Burst: 120}
+	if got, want := longestLimitPeriod(cfg), time.Minute; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+	cfg.limiter.routes = map[string]ratelimit.Limit{
+		"/v1/users/register":        {Rate: 5.0 / (60 * 60), Burst: 5},
+		"/v1/users/resetPassword":   {Rate: 3.0 / (24 * 60 * 60), Burst: 3},
+		"/v1/tokens/authentication": {Rate: 10.0 / (15 * 60), Burst: 10},
+	}
+	if got, want := longestLimitPeriod(cfg), 24*time.Hour; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	app := newTestApplication(t)
+	app.cfg.limiter.enabled = true
+	app.cfg.limiter.defaultLimit = ratelimit.Limit{Rate: 100, Burst: 100}
+	app.cfg.limiter.routes = map[string]ratelimit.Limit{"/v1/tokens/authentication": {Rate: 1.0 / 60, Burst: 2}}
+	app.limiter = ratelimit.NewMemoryStore(longestLimitPeriod(app.cfg))
+	ts := newTestServer(t, app.router())
+
+	credentials := map[string]string{"email": "alice@example.com", "password": "pa55word1234"}
+	for i := 0; i < 2; i++ {
+		status, _ := ts.do(t, http.MethodPost, "/v1/tokens/authentication", "", credentials)
+		if status != http.StatusUnauthorized {
+			t.Fatalf("request %d: got status %d, want %d", i+1, status, http.StatusUnauthorized)
+		}
+	}
+
+	req, err := http.NewRequest(http.MethodPost, ts.URL+"/v1/tokens/authentication", strings.NewReader(`{}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("got status %d, want %d", res.StatusCode, http.StatusTooManyRequests)
+	}
+	for header, want := range map[string]string{
+		"RateLimit-Limit":     "2",
+		"RateLimit-Remaining": "0",
+		"Retry-After":         "60",
+	} {
+		if got := res.Header.Get(header); got != want {
+			t.Errorf("got %s %q, want %q", header, got, want)
+		}
+	}
+
+	status, _ := ts.do(t, http.MethodGet, "/v1/ideas", "", nil)
+	if status != http.StatusOK {
+		t.Errorf("other route: got status %d, want %d", status, http.StatusOK)
+	}
+}
+
+func TestCORS(t *testing.T) {
+	app := newTestApplication(t)
+	app.cfg.cors.trustedOrigins = []string{"https://app.example.com"}
+	ts := newTestServer(t, app.router())
+
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		origin      string
+		wantCode    int
+		wantOrigin  string
+		wantMethods string
+	}{
+		{"preflight", http.MethodOptions, "/v1/ideas/1", "https://app.example.com", http.StatusNoContent, "https://app.example.com", "DELETE, GET, OPTIONS, PUT"},
+		{"untrusted preflight", http.MethodOptions, "/v1/ideas/1", "https://evil.example.com", http.StatusNoContent, "", ""},
+		{"unknown path", http.MethodOptions, "/v1/nothing", "https://app.example.com", http.StatusNotFound, "https://app.example.com", ""},
+		{"simple request", http.MethodGet, "/v1/ideas", "https://app.example.com", http.StatusOK, "https://app.example.com", ""},
+		{"untrusted request", http.MethodGet, "/v1/ideas", "https://evil.example.com", http.StatusOK, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, ts.URL+tt.path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Origin", tt.origin)
+			if tt.method == http.MethodOptions {
+				req.Header.Set("Access-Control-Request-Method", http.MethodDelete)
+			}
+			res, err := ts.Client().Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			res.Body.Close()
+
+			if res.StatusCode != tt.wantCode {
+				t.Errorf("got status %d, want %d", res.StatusCode, tt.wantCode)
+			}
+			if got := res.Header.Get("Access-Control-Allow-Origin"); got != tt.wantOrigin {
+				t.Errorf("got Access-Control-Allow-Origin %q, want %q", got, tt.wantOrigin)
+			}
+			if got := res.Header.Get("Access-Control-Allow-Methods"); got != tt.wantMethods {
+				t.Errorf("got Access-Control-Allow-Methods %q, want %q", got, tt.wantMethods)
+			}
+			if !slices.Contains(res.Header.Values("Vary"), "Origin") {
+				t.Errorf("Vary does not include Origin: %v", res.Header.Values("Vary"))
+			}
+		})
+	}
+}
+
+func TestAcceptsProblem(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   bool
+	}{
+		{"", false},
+		{"application/json", false},
+		{"application/problem+json", true},
+		{"application/json, application/problem+json;q=0.5", true},
+		{"application/problem+json;q=0", false},
+		{"application/problem+json;q=0.0", false},
+		{"application/problem+json;q=0.000", false},
+		{"application/problem+json;q=0.001", true},
+		{"application/problem+json;q=abc", false},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if tt.accept != "" {
+			r.Header.Set("Accept", tt.accept)
+		}
+		if got := acceptsProblem(r); got != tt.want {
+			t.Errorf("acceptsProblem(%q) = %t, want %t", tt.accept, got, tt.want)
+		}
+	}
+}
+
+func TestProblemResponses(t *testing.T) {
+	ts := newTestServer(t, newTestApplication(t).router())
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		wantStatus int
+		wantCode   string
+		wantFields []string
+	}{
+		{"validation", http.MethodPost, "/v1/users/register", `{"username": "alice", "email": "alice"}`, http.StatusBadRequest, "validation_failed", []string{"email", "password", "password"}},
+		{"malformed body", http.MethodPost, "/v1/tokens/authentication", `{`, http.StatusBadRequest, "bad_request", nil},
+		{"unknown route", http.MethodGet, "/v1/nothing", "", http.StatusNotFound, "not_found", nil},
+		{"wrong method", http.MethodPatch, "/v1/ideas", "", http.StatusMethodNotAllowed, "method_not_allowed", nil},
+		{"missing token", http.MethodPost, "/v1/ideas", `{}`, http.StatusUnauthorized, "authentication_required", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, ts.URL+tt.path, strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Accept", "application/problem+json, application/json;q=0.9")
+			res, err := ts.Client().Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer res.Body.Close()
+
+			if res.StatusCode != tt.wantStatus {
+				t.Errorf("got status %d, want %d", res.StatusCode, tt.wantStatus)
+			}
+			if got := res.Header.Get("Content-Type"); got != "application/problem+json" {
+				t.Errorf("got Content-Type %q, want application/problem+json", got)
+			}
+			var p problem
+			if err := json.NewDecoder(res.Body).Decode(&p); err != nil {
+				t.Fatal(err)
+			}
+			if p.Code != tt.wantCode || p.Status != tt.wantStatus || p.Type != "/problems/"+tt.wantCode {
+				t.Errorf("got problem %+v", p)
+			}
+			if p.Instance == "" || p.Instance != res.Header.Get("X-Request-ID") {
+				t.Errorf("got instance %q, want the request id %q", p.Instance, res.Header.Get("X-Request-ID"))
+			}
+			var fields []string
+			for _, e := range p.Errors {
+				fields = append(fields, e.Field)
+			}
+			if !slices.Equal(fields, tt.wantFields) {
+				t.Errorf("got invalid fields %v, want %v", fields, tt.wantFields)
+			}
+		})
+	}
+}
+
+func TestLocalizedErrors(t *testing.T) {
+	ts := newTestServer(t, newTestApplication(t).router())
+	ts.register(t, "bob", "bob@example.com")
+
+	tests := []struct {
+		name         string
+		language     string
+		path         string
+		body         string
+		wantLanguage string
+		wantError    any
+	}{
+		{"spanish credentials", "es-MX,es;q=0.9", "/v1/tokens/authentication", `{"email": "nobody@example.com", "password": "pa55word1234"}`, "es", "credenciales no válidas"},
+		{"french validation", "fr-CH, fr;q=0.9, en;q=0.8", "/v1/users/register", `{"username": "alice", "email": "alice@example.com", "password": "short"}`, "fr", map[string]any{"password": []any{"doit contenir au moins 11 caractères"}}},
+		{"unsupported language", "ja", "/v1/users/register", `{"username": "alice", "email": "alice@example.com", "password": "short"}`, "en", map[string]any{"password": []any{"must be at least 11 characters long"}}},
+		{"no preference", "", "/v1/tokens/authentication", `{"email": "nobody@example.com", "password": "pa55word1234"}`, "en", "invalid credentials"},
+		{"spanish duplicate email", "es", "/v1/users/register", `{"username": "alice", "email": "bob@example.com", "password": "pa55word1234"}`, "es", "ya existe un usuario con esta dirección de correo electrónico"},
+		{"french duplicate username", "fr", "/v1/users/register", `{"username": "bob", "email": "alice@example.com", "password": "pa55word1234"}`, "fr", "un utilisateur avec ce nom d'utilisateur existe déjà"},
+		{"french empty body", "fr", "/v1/tokens/authentication", ``, "fr", "le corps ne doit pas être vide"},
+		{"spanish syntax error", "es", "/v1/tokens/authentication", `{"email": }`, "es", "el cuerpo contiene JSON mal formado en el carácter 11"},
+		{"spanish truncated body", "es", "/v1/tokens/authentication", `{"email": "a`, "es", "el cuerpo contiene JSON mal formado"},
+		{"french unknown field", "fr", "/v1/tokens/authentication", `{"name": "alice"}`, "fr", `le JSON contient un champ inconnu : "name"`},
+		{"spanish field type", "es", "/v1/tokens/authentication", `{"email": 1}`, "es", "tipo no válido para el campo email"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPost, ts.URL+tt.path, strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Accept-Language", tt.language)
+			res, err := ts.Client().Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer res.Body.Close()
+
+			if got := res.Header.Get("Content-Language"); got != tt.wantLanguage {
+				t.Errorf("got Content-Language %q, want %q", got, tt.wantLanguage)
+			}
+			var body map[string]any
+			if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(body["error"], tt.wantError) {
+				t.Errorf("got error %v, want %v", body["error"], tt.wantError)
+			}
+		})
+	}
+}
+
+func TestFollowYourself(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.router())
+	token := ts.register(t, "alice", "alice@example.com")
+	user, err := app.models.User.GetByEmail(context.Background(), "alice@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/v1/profiles/%d/follow", ts.URL, user.Id), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Accept-Language", "fr")
+	res, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	var body map[string]any
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusBadRequest || body["error"] != "vous ne pouvez pas vous suivre vous-même" {
+		t.Errorf("got status %d and error %v", res.StatusCode, body["error"])
+	}
+}
+
+func TestUnsubscribeDigest(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.router())
+	ts.register(t, "alice", "alice@example.com")
+	user, err := app.models.User.GetByEmail(context.Background(), "alice@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Signed with an empty key, as anyone could when no secret is set.
+	forged := fmt.Sprintf("%d.%s", user.Id, app.unsubscribeSignature(user.Id))
+
+	path := "/v1/users/digest/unsubscribe?token=" + url.QueryEscape(forged)
+	status, _ := ts.do(t, http.MethodPost, path, "", nil)
+	if status != http.StatusUnauthorized {
+		t.Errorf("token without a secret: got status %d, want %d", status, http.StatusUnauthorized)
+	}
+
+	app.cfg.digestSecret = "secret"
+	status, _ = ts.do(t, http.MethodPost, path, "", nil)
+	if status != http.StatusUnauthorized {
+		t.Errorf("forged token: got status %d, want %d", status, http.StatusUnauthorized)
+	}
+	status, _ = ts.do(t, http.MethodPost, "/v1/users/digest/unsubscribe?token=garbage", "", nil)
+	if status != http.StatusUnauthorized {
+		t.Errorf("malformed token: got status %d, want %d", status, http.StatusUnauthorized)
+	}
+
+	valid := fmt.Sprintf("%d.%s", user.Id, app.unsubscribeSignature(user.Id))
+	status, body := ts.do(t, http.MethodPost, "/v1/users/digest/unsubscribe?token="+url.QueryEscape(valid), "", nil)
+	if status != http.StatusOK {
+		t.Errorf("valid token: got status %d, want %d: %v", status, http.StatusOK, body)
+	}
+}
+
+func TestSendDigestsJob(t *testing.T) {
+	app := newTestApplication(t)
+	app.cfg.digestSecret = "secret"
+	ts := newTestServer(t, app.router())
+	alice := ts.register(t, "alice", "alice@example.com")
+	bob := ts.register(t, "bob", "bob@example.com")
+	carol := ts.register(t, "carol", "carol@example.com")
+	for _, token := range []string{alice, carol} {
+		status, body := ts.do(t, http.MethodPut, "/v1/users/me/digest", token, map[string]string{"frequency": "daily"})
+		if status != http.StatusOK {
+			t.Fatalf("subscribe: got status %d: %v", status, body)
+		}
+	}
+	for _, idea := range []struct{ token, title, tag string }{
+		{bob, "Bob's rust idea", "rust"},
+		{carol, "Carol's go idea", "go"},
+		{carol, "Carol's rust idea", "rust"},
+	} {
+		status, body := ts.do(t, http.MethodPost, "/v1/ideas", idea.token, map[string]any{
+			"title":       idea.title,
+			"description": "Something",
+			"tags":        []map[string]string{{"title": idea.tag}},
+		})
+		if status != http.StatusCreated {
+			t.Fatalf("create %q: got status %d: %v", idea.title, status, body)
+		}
+	}
+	for _, path := range []string{fmt.Sprintf("/v1/profiles/%d/follow", userId(t, app, "bob@example.com")), "/v1/tags/go/follow"} {
+		status, body := ts.do(t, http.MethodPost, path, alice, nil)
+		if status != http.StatusOK {
+			t.Fatalf("follow %s: got status %d: %v", path, status, body)
+		}
+	}
+
+	app.sendDigestsJob()
+	msg := waitForMail(t, app, 1)[0]
+	if msg.To != "alice@example.com" {
+		t.Errorf("digest sent to %s, want alice@example.com", msg.To)
+	}
+	for _, want := range []string{"Carol's go idea", "Bob's rust idea"} {
+		if !strings.Contains(msg.PlainBody, want) {
+			t.Errorf("digest does not list %q:\n%s", want, msg.PlainBody)
+		}
+	}
+	if strings.Contains(msg.PlainBody, "Carol's rust idea") {
+		t.Errorf("digest lists an idea alice follows neither by author nor tag:\n%s", msg.PlainBody)
+	}
+	if !strings.HasPrefix(msg.Headers["List-Unsubscribe"], "<") {
+		t.Errorf("got List-Unsubscribe %q", msg.Headers["List-Unsubscribe"])
+	}
+
+	// Both digests were marked sent, including carol's empty one, so none is
+	// due until the next period.
+	digests, err := app.models.Digest.Due(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(digests) != 0 {
+		t.Errorf("got %d digests due right after sending, want none", len(digests))
+	}
+	app.sendDigestsJob()
+	time.Sleep(50 * time.Millisecond)
+	if n := len(app.mailCatcher.Messages()); n != 1 {
+		t.Errorf("got %d messages after sending digests twice, want 1", n)
+	}
+}
+
+func TestDigestSkipsBannedUsers(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.router())
+	ctx := context.Background()
+	for _, name := range []string{"alice", "bob"} {
+		token := ts.register(t, name, name+"@example.com")
+		status, body := ts.do(t, http.MethodPut, "/v1/users/me/digest", token, map[string]string{"frequency": "daily"})
+		if status != http.StatusOK {
+			t.Fatalf("subscribe %s: got status %d: %v", name, status, body)
+		}
+	}
+	err := app.admin(io.Discard, []string{"users", "ban", "bob@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	digests, err := app.models.Digest.Due(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(digests) != 1 || digests[0].User.Username != "alice" {
+		t.Errorf("got digests %+v, want one for alice", digests)
+	}
+}
+
+func TestExportUserData(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.router())
+	token := ts.register(t, "alice", "alice@example.com")
+	status, _ := ts.do(t, http.MethodPost, "/v1/ideas", token, map[string]any{
+		"title":       "Tagged",
+		"description": "An idea with a tag",
+		"tags":        []map[string]string{{"title": "go"}},
+	})
+	if status != http.StatusCreated {
+		t.Fatalf("create idea: got status %d", status)
+	}
+	user, err := app.models.User.GetByEmail(context.Background(), "alice@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = app.models.Idea.Insert(context.Background(), &data.Idea{Title: "Untagged", Description: "An idea without tags", UserId: user.Id})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, ts.URL+"/v1/users/me/export", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	res, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("got status %d: %s", res.StatusCode, body)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := zr.Open("ideas.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var ideas []struct {
+		Title string     `json:"title"`
+		Tags  []data.Tag `json:"tags"`
+	}
+	if err := json.NewDecoder(f).Decode(&ideas); err != nil {
+		t.Fatal(err)
+	}
+	titles := make([]string, len(ideas))
+	for i, idea := range ideas {
+		titles[i] = idea.Title
+	}
+	if !slices.Equal(titles, []string{"Untagged", "Tagged"}) {
+		t.Errorf("got ideas %q, want both newest first", titles)
+	}
+}
+
+func TestAccountDeletion(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.router())
+	token := ts.register(t, "alice", "alice@example.com")
+	login := map[string]string{"email": "alice@example.com", "password": "pa55word1234"}
+	deletion := map[string]any{"password": "pa55word1234"}
+
+	status, body := ts.do(t, http.MethodDelete, "/v1/users/me", token, deletion)
+	if status != http.StatusAccepted {
+		t.Fatalf("schedule deletion: got status %d: %v", status, body)
+	}
+	status, _ = ts.do(t, http.MethodGet, "/v1/feed", token, nil)
+	if status != http.StatusUnauthorized {
+		t.Errorf("session after scheduling deletion: got status %d, want %d", status, http.StatusUnauthorized)
+	}
+
+	status, body = ts.do(t, http.MethodPost, "/v1/tokens/authentication", "", login)
+	if status != http.StatusOK {
+		t.Fatalf("login during the grace period: got status %d: %v", status, body)
+	}
+	token = body["token"].(string)
+	stats, err := app.models.Admin.Stats(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.PendingDeletion != 1 {
+		t.Errorf("logging in cancelled the deletion: %d pending, want 1", stats.PendingDeletion)
+	}
+	status, body = ts.do(t, http.MethodDelete, "/v1/users/me/deletion", token, nil)
+	if status != http.StatusOK {
+		t.Fatalf("cancel deletion: got status %d: %v", status, body)
+	}
+	status, _ = ts.do(t, http.MethodDelete, "/v1/users/me/deletion", token, nil)
+	if status != http.StatusNotFound {
+		t.Errorf("cancel without a pending deletion: got status %d, want %d", status, http.StatusNotFound)
+	}
+
+	// Without a grace period the deletion is due at once.
+	app.cfg.deletionGracePeriod = 0
+	deleted, err := app.models.User.DeleteScheduled(context.Background())
+	if err != nil || deleted != 0 {
+		t.Fatalf("cancelled account: deleted %d, err %v", deleted, err)
+	}
+	status, _ = ts.do(t, http.MethodDelete, "/v1/users/me", token, deletion)
+	if status != http.StatusAccepted {
+		t.Fatalf("schedule deletion again: got status %d", status)
+	}
+	deleted, err = app.models.User.DeleteScheduled(context.Background())
+	if err != nil || deleted != 1 {
+		t.Fatalf("due account: deleted %d, err %v", deleted, err)
+	}
+	status, _ = ts.do(t, http.MethodPost, "/v1/tokens/authentication", "", login)
+	if status != http.StatusUnauthorized {
+		t.Errorf("login after deletion: got status %d, want %d", status, http.StatusUnauthorized)
+	}
+}
+
+func TestDebugMail(t *testing.T) {
+	app := newTestApplication(t)
+	app.cfg.dev = true
+	ts := newTestServer(t, app.router())
+	ts.register(t, "alice", "alice@example.com")
+	ts.register(t, "bob", "bob@example.com")
+	for i, email := range []string{"alice@example.com", "bob@example.com"} {
+		status, _ := ts.do(t, http.MethodPost, "/v1/users/sendResetPassword", "", map[string]string{"email": email})
+		if status != http.StatusOK {
+			t.Fatalf("send reset token: got status %d", status)
+		}
+		waitForMail(t, app, i+1)
+	}
+
+	status, body := ts.do(t, http.MethodGet, "/debug/mail?format=json", "", nil)
+	if status != http.StatusOK {
+		t.Fatalf("got status %d", status)
+	}
+	messages, _ := body["messages"].([]any)
+	if len(messages) != 2 || messages[0].(map[string]any)["to"] != "bob@example.com" {
+		t.Errorf("got messages %v, want the newest first", messages)
+	}
+
+	res, err := ts.Client().Get(ts.URL + "/debug/mail")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	page, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ct := res.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/html") {
+		t.Errorf("got Content-Type %q", ct)
+	}
+	if !bytes.Contains(page, []byte("Sent mail (2)")) || !bytes.Contains(page, []byte("Reset your password")) {
+		t.Errorf("page does not list the sent mail:\n%s", page)
+	}
+}
@@ -1,24 +1,35 @@
 package main
 
 import (
+	"archive/zip"
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"sort"
 	"strconv"
 	"strings"
 
 	"github.com/julienschmidt/httprouter"
+	"github.com/sulavmhrzn/projectideas/internal/validator"
 )
 
 func (app *application) writeJSON(w http.ResponseWriter, status int, data any) error {
+	return app.writeJSONAs(w, status, "application/json", data)
+}
+
+// writeJSONAs is writeJSON for JSON based media types such as
+// application/problem+json.
+func (app *application) writeJSONAs(w http.ResponseWriter, status int, contentType string, data any) error {
 	js, err := json.MarshalIndent(data, "", "\t")
 	if err != nil {
 		return err
 	}
 	js = append(js, '\n')
-	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Type", contentType)
 	w.WriteHeader(status)
 	_, err = w.Write(js)
 	if err != nil {
@@ -27,6 +38,43 @@ func (app *application) writeJSON(w http.ResponseWriter, status int, data any) e
 	return nil
 }
 
+// writeZIP sends files as a zip archive attachment, encoding each value as
+// JSON under its file name.
+func (app *application) writeZIP(w http.ResponseWriter, status int, filename string, files map[string]any) error {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, name := range names {
+		js, err := json.MarshalIndent(files[name], "", "\t")
+		if err != nil {
+			return err
+		}
+		f, err := zw.Create(name)
+		if err != nil {
+			return err
+		}
+		_, err = f.Write(append(js, '\n'))
+		if err != nil {
+			return err
+		}
+	}
+	err := zw.Close()
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.WriteHeader(status)
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
 func (app *application) readJSON(w http.ResponseWriter, r *http.Request, input any) error {
 	dec := json.NewDecoder(r.Body)
 	dec.DisallowUnknownFields()
@@ -39,22 +87,28 @@ func (app *application) readJSON(w http.ResponseWriter, r *http.Request, input a
 		var maxBytesReaderError *http.MaxBytesError
 		switch {
 		case errors.Is(err, io.EOF):
-			return fmt.Errorf("body must not be empty")
+			return &requestError{code: msgBodyEmpty}
 		case errors.As(err, &syntaxError):
-			return fmt.Errorf("syntax error at character (%d)", syntaxError.Offset)
+			return &requestError{code: msgBodySyntax, args: []any{syntaxError.Offset}}
+		case errors.Is(err, io.ErrUnexpectedEOF):
+			return &requestError{code: msgBodyMalformed}
 		case errors.As(err, &unmarshalTypeError):
-			return fmt.Errorf("invalid type for field %s", unmarshalTypeError.Field)
+			return &requestError{code: msgBodyFieldType, args: []any{unmarshalTypeError.Field}}
 		case strings.HasPrefix(err.Error(), "json: unknown field"):
 			unknownField := strings.TrimPrefix(err.Error(), "json: unknown field ")
-			return fmt.Errorf("json contains an unknown field: %s", unknownField)
+			return &requestError{code: msgBodyUnknownField, args: []any{unknownField}}
 		case errors.As(err, &maxBytesReaderError):
-			return fmt.Errorf("request body is too large")
+			return &requestError{code: msgBodyTooLarge, args: []any{maxBytesReaderError.Limit}}
 		case errors.As(err, &invalidUnmarshalError):
 			panic(err)
 		default:
 			return err
 		}
 	}
+	err = dec.Decode(&struct{}{})
+	if !errors.Is(err, io.EOF) {
+		return &requestError{code: msgBodyTrailingData}
+	}
 	return nil
 }
 
@@ -66,3 +120,41 @@ func (app *application) readIDParam(r *http.Request) (int, error) {
 	}
 	return id, nil
 }
+
+func (app *application) readStringParam(r *http.Request, name string) string {
+	return httprouter.ParamsFromContext(r.Context()).ByName(name)
+}
+
+func (app *application) readString(qs url.Values, key string, defaultValue string) string {
+	s := qs.Get(key)
+	if s == "" {
+		return defaultValue
+	}
+	return s
+}
+
+func (app *application) readInt(qs url.Values, key string, defaultValue int, v *validator.Validator) int {
+	s := qs.Get(key)
+	if s == "" {
+		return defaultValue
+	}
+	i, err := strconv.Atoi(s)
+	if err != nil {
+		v.AddError(key, validator.NotAnInteger)
+		return defaultValue
+	}
+	return i
+}
+
+func (app *application) readBool(qs url.Values, key string, defaultValue bool, v *validator.Validator) bool {
+	s := qs.Get(key)
+	if s == "" {
+		return defaultValue
+	}
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		v.AddError(key, validator.NotABoolean)
+		return defaultValue
+	}
+	return b
+}
@@ -32,7 +32,7 @@ func (app *application) createIdeaHandler(w http.ResponseWriter, r *http.Request
 		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
-	idea, err = app.models.Idea.Insert(idea)
+	idea, err = app.models.Idea.Insert(r.Context(), idea)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -45,7 +45,7 @@ func (app *application) createIdeaHandler(w http.ResponseWriter, r *http.Request
 }
 
 func (app *application) listIdeasHandler(w http.ResponseWriter, r *http.Request) {
-	ideas, err := app.models.Idea.List()
+	ideas, err := app.models.Idea.List(r.Context())
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -62,7 +62,7 @@ func (app *application) getIdeaHandler(w http.ResponseWriter, r *http.Request) {
 		app.notFoundResponse(w, r)
 		return
 	}
-	idea, err := app.models.Idea.Get(id)
+	idea, err := app.models.Idea.Get(r.Context(), id)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrNoRows):
@@ -85,7 +85,7 @@ func (app *application) deleteIdeaHandler(w http.ResponseWriter, r *http.Request
 		app.notFoundResponse(w, r)
 		return
 	}
-	err = app.models.Idea.Delete(id, user.Id)
+	err = app.models.Idea.Delete(r.Context(), id, user.Id)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrNoRows):
@@ -117,7 +117,7 @@ func (app *application) updateIdeaHandler(w http.ResponseWriter, r *http.Request
 		app.badRequestResponse(w, r, err)
 		return
 	}
-	idea, err := app.models.Idea.Get(id)
+	idea, err := app.models.Idea.Get(r.Context(), id)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrNoRows):
@@ -141,7 +141,7 @@ func (app *application) updateIdeaHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	idea, err = app.models.Idea.Update(id, user.Id, idea)
+	idea, err = app.models.Idea.Update(r.Context(), id, user.Id, idea)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrNoRows):
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// schedule runs job in the background every interval until the server shuts
+// down.
+func (app *application) schedule(interval time.Duration, job func()) {
+	app.background(func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-app.stop:
+				return
+			case <-ticker.C:
+				job()
+			}
+		}
+	})
+}
+
+func (app *application) deleteScheduledUsersJob() {
+	deleted, err := app.models.User.DeleteScheduled(context.Background())
+	if err != nil {
+		app.logJobError(err)
+		return
+	}
+	if deleted > 0 {
+		app.logger.Info("deleted scheduled user accounts", "count", deleted)
+	}
+}
+
+// purgeBatch is how many rows a cleanup job deletes per statement, so that
+// clearing a large backlog does not hold locks for long.
+const purgeBatch = 1000
+
+// deleteInBatches calls del until it deletes fewer than purgeBatch rows or
+// ctx is done, and returns how many rows were deleted.
+func deleteInBatches(ctx context.Context, del func(ctx context.Context, limit int) (int64, error)) (int64, error) {
+	var total int64
+	for ctx.Err() == nil {
+		n, err := del(ctx, purgeBatch)
+		total += n
+		if err != nil || n < purgeBatch {
+			return total, err
+		}
+	}
+	return total, ctx.Err()
+}
+
+func (app *application) purgeExpiredTokens(ctx context.Context) (int64, error) {
+	return deleteInBatches(ctx, app.models.Token.DeleteExpired)
+}
+
+// stopContext returns a context that is cancelled when the server shuts
+// down, so that long jobs can stop between batches.
+func (app *application) stopContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		select {
+		case <-app.stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
+}
+
+func (app *application) deleteExpiredTokensJob() {
+	ctx, cancel := app.stopContext()
+	defer cancel()
+
+	start := time.Now()
+	deleted, err := app.purgeExpiredTokens(ctx)
+	app.metrics.tokensPurged.Add(float64(deleted))
+	if err != nil && !errors.Is(err, context.Canceled) {
+		app.logJobError(err)
+	}
+	if deleted > 0 {
+		app.logger.Info("deleted expired tokens", "count", deleted, "duration", time.Since(start))
+	}
+}
+
+// deleteOldMailJob removes delivered and dead-lettered emails once the
+// retention period has passed.
+func (app *application) deleteOldMailJob() {
+	ctx, cancel := app.stopContext()
+	defer cancel()
+
+	start := time.Now()
+	deleted, err := deleteInBatches(ctx, func(ctx context.Context, limit int) (int64, error) {
+		return app.mailQueue.DeleteFinished(ctx, app.cfg.mailer.retention, limit)
+	})
+	app.metrics.mailPurged.Add(float64(deleted))
+	if err != nil && !errors.Is(err, context.Canceled) {
+		app.logJobError(err)
+	}
+	if deleted > 0 {
+		app.logger.Info("deleted old emails", "count", deleted, "duration", time.Since(start))
+	}
+}
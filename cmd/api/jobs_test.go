@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sulavmhrzn/projectideas/internal/data"
+)
+
+func TestDeleteExpiredTokensJob(t *testing.T) {
+	app := newTestApplication(t)
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		_, err := app.models.Token.New(ctx, 1, -time.Minute, data.ScopeAuthentication)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, err := app.models.Token.New(ctx, 1, time.Hour, data.ScopePasswordReset)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app.deleteExpiredTokensJob()
+
+	tokens, err := app.models.Token.ListForUser(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tokens) != 1 || tokens[0].Scope != data.ScopePasswordReset {
+		t.Errorf("got tokens %+v, want the unexpired one", tokens)
+	}
+
+	rr := httptest.NewRecorder()
+	app.metrics.handler().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if !strings.Contains(rr.Body.String(), "\ntokens_purged_total 3\n") {
+		t.Errorf("metrics do not report 3 purged tokens:\n%s", rr.Body.String())
+	}
+}
+
+func TestPurgeExpiredTokensStops(t *testing.T) {
+	app := newTestApplication(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := app.purgeExpiredTokens(ctx)
+	if err != context.Canceled {
+		t.Errorf("got error %v, want %v", err, context.Canceled)
+	}
+}
+
+type failingUsers struct {
+	data.UserRepository
+}
+
+func (failingUsers) DeleteScheduled(ctx context.Context) (int64, error) {
+	return 0, errors.New("boom")
+}
+
+func TestJobErrorSource(t *testing.T) {
+	app := newTestApplication(t)
+	var logs bytes.Buffer
+	app.logger = slog.New(slog.NewTextHandler(&logs, nil))
+	app.models.User = failingUsers{app.models.User}
+
+	app.deleteScheduledUsersJob()
+
+	if !strings.Contains(logs.String(), "msg=boom source=") || !strings.Contains(logs.String(), "/jobs.go:") {
+		t.Errorf("error was not logged with the job as its source: %s", logs.String())
+	}
+}
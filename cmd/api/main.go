@@ -1,41 +1,87 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"flag"
 	"fmt"
 	"log"
-	"net/http"
+	"log/slog"
+	"net/netip"
 	"os"
 	"strconv"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/XSAM/otelsql"
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
 	"github.com/sulavmhrzn/projectideas/internal/data"
-)
-
-const (
-	reset = "\033[0m"
-	red   = "\033[31m"
-	green = "\033[32m"
+	"github.com/sulavmhrzn/projectideas/internal/mailer"
+	"github.com/sulavmhrzn/projectideas/internal/ratelimit"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
 )
 
 type config struct {
-	port   int
-	dsn    string
+	port                int
+	dev                 bool
+	logFormat           string
+	dsn                 string
+	dbQueryTimeout      time.Duration
+	autoMigrate         bool
+	baseURL             string
+	digestSecret        string
+	deletionGracePeriod time.Duration
+	shutdownTimeout     time.Duration
+	server              struct {
+		readHeaderTimeout time.Duration
+		readTimeout       time.Duration
+		writeTimeout      time.Duration
+		idleTimeout       time.Duration
+		maxHeaderBytes    int
+		maxBodyBytes      int64
+	}
+	limiter struct {
+		enabled        bool
+		store          string
+		trustedProxies []netip.Prefix
+		defaultLimit   ratelimit.Limit
+		routes         map[string]ratelimit.Limit
+	}
+	cors struct {
+		trustedOrigins []string
+	}
 	mailer struct {
 		host      string
 		port      int
 		username  string
 		password  string
 		EmailFrom string
+		transport string
+		file      string
+		retention time.Duration
+	}
+	tracing struct {
+		exporter    string
+		endpoint    string
+		sampleRatio float64
 	}
 }
 type application struct {
-	cfg      config
-	infoLog  *log.Logger
-	errorLog *log.Logger
-	models   data.Model
+	cfg         config
+	logger      *slog.Logger
+	models      data.Model
+	mailQueue   *mailer.Queue
+	mailCatcher *mailer.Recorder
+	metrics     *metrics
+	limiter     ratelimit.Store
+	// shutdownTracing flushes spans that have not been exported yet.
+	shutdownTracing func(context.Context) error
+	// stop is closed on shutdown to end scheduled jobs; wg tracks the
+	// goroutines started with background.
+	stop chan struct{}
+	wg   sync.WaitGroup
 }
 
 func main() {
@@ -48,45 +94,182 @@ func main() {
 	if err != nil {
 		log.Fatal(err)
 	}
-	mailerPort, err := strconv.Atoi(os.Getenv("MAILER_PORT"))
-	if err != nil {
-		log.Fatal(err)
+	mailerPort := 25
+	if os.Getenv("MAILER_PORT") != "" {
+		mailerPort, err = strconv.Atoi(os.Getenv("MAILER_PORT"))
+		if err != nil {
+			log.Fatal(err)
+		}
 	}
 
 	flag.IntVar(&cfg.port, "port", port, "port to listen")
+	flag.BoolVar(&cfg.dev, "dev", false, "development mode: keep sent mail in memory and serve it at /debug/mail")
+	flag.StringVar(&cfg.logFormat, "log-format", "text", "log output format (text|json)")
 	flag.StringVar(&cfg.dsn, "dsn", os.Getenv("DSN"), "Database dsn")
+	flag.DurationVar(&cfg.dbQueryTimeout, "db-query-timeout", 5*time.Second, "maximum time a database query may run")
+	flag.BoolVar(&cfg.autoMigrate, "auto-migrate", false, "apply pending database migrations on startup")
+	flag.StringVar(&cfg.baseURL, "base-url", os.Getenv("BASE_URL"), "public URL of the api used in emails")
+	flag.StringVar(&cfg.digestSecret, "digest-secret", os.Getenv("DIGEST_SECRET"), "key used to sign digest unsubscribe links")
+	flag.DurationVar(&cfg.deletionGracePeriod, "deletion-grace-period", 30*24*time.Hour, "time before a deleted account is removed")
+	flag.DurationVar(&cfg.shutdownTimeout, "shutdown-timeout", 30*time.Second, "time allowed for requests and background tasks to finish on shutdown")
+	flag.DurationVar(&cfg.server.readHeaderTimeout, "read-header-timeout", 5*time.Second, "maximum time to read request headers")
+	flag.DurationVar(&cfg.server.readTimeout, "read-timeout", 15*time.Second, "maximum time to read a whole request")
+	flag.DurationVar(&cfg.server.writeTimeout, "write-timeout", 30*time.Second, "maximum time to write a response")
+	flag.DurationVar(&cfg.server.idleTimeout, "idle-timeout", 2*time.Minute, "maximum time to keep an idle connection open")
+	flag.IntVar(&cfg.server.maxHeaderBytes, "max-header-bytes", 64<<10, "maximum size of request headers in bytes")
+	flag.Int64Var(&cfg.server.maxBodyBytes, "max-body-bytes", 1<<20, "maximum size of a request body in bytes")
 	flag.StringVar(&cfg.mailer.host, "mailer-host", os.Getenv("MAILER_HOST"), "mailer host")
 	flag.IntVar(&cfg.mailer.port, "mailer-port", mailerPort, "mailer port")
 	flag.StringVar(&cfg.mailer.username, "mailer-username", os.Getenv("MAILER_USERNAME"), "mailer username")
 	flag.StringVar(&cfg.mailer.password, "mailer-password", os.Getenv("MAILER_PASSWORD"), "mailer password")
 	flag.StringVar(&cfg.mailer.EmailFrom, "mailer-email-from", os.Getenv("MAILER_EMAIL_FROM"), "mailer email from")
+	flag.StringVar(&cfg.mailer.transport, "mailer-transport", "smtp", "mailer transport (smtp|file)")
+	flag.StringVar(&cfg.mailer.file, "mailer-file", "mail.mbox", "mbox file written by the file mailer transport")
+	flag.DurationVar(&cfg.mailer.retention, "mailer-retention", 7*24*time.Hour, "time delivered and dead-lettered emails are kept")
+	flag.StringVar(&cfg.tracing.exporter, "trace-exporter", "none", "trace exporter (none|stdout|otlp)")
+	flag.StringVar(&cfg.tracing.endpoint, "trace-endpoint", "", "OTLP/HTTP endpoint URL, defaults to OTEL_EXPORTER_OTLP_ENDPOINT")
+	flag.Float64Var(&cfg.tracing.sampleRatio, "trace-sample-ratio", 1, "fraction of new traces to sample")
+	flag.BoolVar(&cfg.limiter.enabled, "limiter-enabled", true, "enable rate limiting")
+	flag.StringVar(&cfg.limiter.store, "limiter-store", "memory", "where rate limit buckets are kept (memory|postgres)")
+	trustedProxies := flag.String("limiter-trusted-proxies", "", "comma separated addresses or CIDR ranges of proxies whose X-Forwarded-For is trusted")
+	defaultLimit := flag.String("limiter-default", "120/1m", "rate limit per client for routes without their own limit")
+	routeLimits := flag.String("limiter-routes", "/v1/users/register=5/1h,/v1/tokens/authentication=10/15m,/v1/users/sendResetPassword=3/1h,/v1/users/resetPassword=10/1h", "comma separated <route>=<limit> rate limits per client")
+	trustedOrigins := flag.String("cors-trusted-origins", "", "comma separated origins allowed to make cross-origin requests, or * for any")
 	flag.Parse()
 
-	db, err := openDB(cfg)
+	for _, origin := range strings.Split(*trustedOrigins, ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			cfg.cors.trustedOrigins = append(cfg.cors.trustedOrigins, origin)
+		}
+	}
+
+	cfg.limiter.trustedProxies, err = parseTrustedProxies(*trustedProxies)
 	if err != nil {
 		log.Fatal(err)
 	}
+	cfg.limiter.defaultLimit, err = ratelimit.ParseLimit(*defaultLimit)
+	if err != nil {
+		log.Fatal(err)
+	}
+	cfg.limiter.routes, err = parseRouteLimits(*routeLimits)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	logger, err := newLogger(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	switch flag.Arg(0) {
+	case "migrate", "admin":
+		app := &application{cfg: cfg, logger: logger}
+		run := app.runMigrate
+		if flag.Arg(0) == "admin" {
+			run = app.runAdmin
+		}
+		err = run(flag.Args()[1:])
+		if err != nil {
+			logger.Error(err.Error())
+			os.Exit(1)
+		}
+		return
+	}
+	shutdownTracing, err := setupTracing(cfg)
+	if err != nil {
+		logger.Error(err.Error())
+		os.Exit(1)
+	}
+	db, err := openDB(cfg)
+	if err != nil {
+		logger.Error(err.Error())
+		os.Exit(1)
+	}
+	if cfg.autoMigrate {
+		err = autoMigrate(db, logger)
+		if err != nil {
+			logger.Error(err.Error())
+			os.Exit(1)
+		}
+	}
 	app := &application{
-		cfg:      cfg,
-		infoLog:  log.New(os.Stdout, fmt.Sprintf("%sINFO: %s", green, reset), log.Ldate|log.Ltime|log.Lshortfile),
-		errorLog: log.New(os.Stderr, fmt.Sprintf("%sERROR: %s", red, reset), log.Ldate|log.Ltime|log.Lshortfile),
-		models:   data.NewModel(db),
+		cfg:             cfg,
+		logger:          logger,
+		models:          data.NewModel(db, cfg.dbQueryTimeout),
+		shutdownTracing: shutdownTracing,
+		stop:            make(chan struct{}),
+	}
+	var transport mailer.Mailer
+	if cfg.dev {
+		app.mailCatcher = &mailer.Recorder{}
+		transport = app.mailCatcher
+		app.logger.Info("development mode: sent mail is kept in memory", "url", fmt.Sprintf("http://localhost:%d/debug/mail", cfg.port))
+	} else {
+		transport, err = newMailer(cfg)
+		if err != nil {
+			app.logger.Error(err.Error())
+			os.Exit(1)
+		}
+	}
+	app.mailQueue = mailer.NewQueue(&mailer.PostgresStore{DB: db, QueryTimeout: cfg.dbQueryTimeout}, transport, app.logger)
+	app.metrics = newMetrics()
+	app.metrics.registerState(db, app.models, app.mailQueue, app.logger)
+	app.background(app.mailQueue.Run)
+	switch cfg.limiter.store {
+	case "memory":
+		app.limiter = ratelimit.NewMemoryStore(longestLimitPeriod(cfg))
+	case "postgres":
+		app.limiter = &ratelimit.PostgresStore{DB: db, QueryTimeout: cfg.dbQueryTimeout}
+		app.schedule(time.Hour, app.deleteStaleRateLimitsJob)
+	default:
+		app.logger.Error(fmt.Sprintf("unknown rate limiter store %q", cfg.limiter.store))
+		os.Exit(1)
 	}
 
-	app.infoLog.Println("database connection successful")
-	server := &http.Server{
-		Addr:    fmt.Sprintf(":%d", app.cfg.port),
-		Handler: app.router(),
+	app.logger.Info("database connection successful")
+	app.schedule(time.Hour, app.deleteScheduledUsersJob)
+	app.schedule(time.Hour, app.deleteExpiredTokensJob)
+	app.schedule(time.Hour, app.deleteOldMailJob)
+	if cfg.digestSecret != "" {
+		app.schedule(time.Hour, app.sendDigestsJob)
+	} else {
+		app.logger.Info("digest emails disabled: no digest secret configured")
 	}
-	app.infoLog.Printf("server running on port %s", server.Addr)
-	err = server.ListenAndServe()
+
+	err = app.serve()
 	if err != nil {
-		app.errorLog.Fatal(err)
+		app.logger.Error(err.Error())
+		os.Exit(1)
+	}
+	db.Close()
+}
+
+func newLogger(cfg config) (*slog.Logger, error) {
+	switch cfg.logFormat {
+	case "text":
+		return slog.New(slog.NewTextHandler(os.Stdout, nil)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(os.Stdout, nil)), nil
+	default:
+		return nil, fmt.Errorf("unknown log format %q", cfg.logFormat)
+	}
+}
+
+func newMailer(cfg config) (mailer.Mailer, error) {
+	switch cfg.mailer.transport {
+	case "smtp":
+		return mailer.NewSMTPMailer(cfg.mailer.host, cfg.mailer.port, cfg.mailer.username, cfg.mailer.password), nil
+	case "file":
+		return mailer.NewFileMailer(cfg.mailer.file), nil
+	default:
+		return nil, fmt.Errorf("unknown mailer transport %q", cfg.mailer.transport)
 	}
 }
 
 func openDB(cfg config) (*sql.DB, error) {
-	db, err := sql.Open("postgres", cfg.dsn)
+	db, err := otelsql.Open("postgres", cfg.dsn,
+		otelsql.WithAttributes(semconv.DBSystemPostgreSQL),
+		otelsql.WithSpanOptions(otelsql.SpanOptions{OmitConnResetSession: true, OmitRows: true}),
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/sulavmhrzn/projectideas/internal/data"
+	"github.com/sulavmhrzn/projectideas/internal/mailer"
+)
+
+type metrics struct {
+	registry       *prometheus.Registry
+	requests       *prometheus.CounterVec
+	requestLatency *prometheus.HistogramVec
+	mailDeliveries *prometheus.CounterVec
+	tokensPurged   prometheus.Counter
+	mailPurged     prometheus.Counter
+}
+
+func newMetrics() *metrics {
+	m := &metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "Number of HTTP requests by route, method and status.",
+		}, []string{"route", "method", "status"}),
+		requestLatency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "Time taken to serve HTTP requests by route, method and status.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"route", "method", "status"}),
+		mailDeliveries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "mail_deliveries_total",
+			Help: "Email delivery attempts by outcome.",
+		}, []string{"outcome"}),
+		tokensPurged: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "tokens_purged_total",
+			Help: "Number of expired tokens deleted by the cleanup job.",
+		}),
+		mailPurged: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "mail_purged_total",
+			Help: "Number of delivered or dead-lettered emails deleted after the retention period.",
+		}),
+	}
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requests,
+		m.requestLatency,
+		m.mailDeliveries,
+		m.tokensPurged,
+		m.mailPurged,
+	)
+	return m
+}
+
+// registerState adds the collectors that read from the database and hooks the
+// delivery counter into the mail queue.
+func (m *metrics) registerState(db *sql.DB, models data.Model, queue *mailer.Queue, logger *slog.Logger) {
+	m.registry.MustRegister(
+		collectors.NewDBStatsCollector(db, "postgres"),
+		&stateCollector{models: models, queue: queue, logger: logger},
+	)
+	queue.OnDelivery = func(outcome string) {
+		m.mailDeliveries.WithLabelValues(outcome).Inc()
+	}
+}
+
+func (m *metrics) handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+func (m *metrics) observeRequest(method, route string, status int, latency time.Duration) {
+	labels := []string{route, method, strconv.Itoa(status)}
+	m.requests.WithLabelValues(labels...).Inc()
+	m.requestLatency.WithLabelValues(labels...).Observe(latency.Seconds())
+}
+
+// stateCollector reads values that live in the database when scraped.
+type stateCollector struct {
+	models data.Model
+	queue  *mailer.Queue
+	logger *slog.Logger
+}
+
+var (
+	activeTokensDesc = prometheus.NewDesc("tokens_active", "Number of unexpired tokens by scope.", []string{"scope"}, nil)
+	mailPendingDesc  = prometheus.NewDesc("mail_queue_pending", "Number of emails waiting to be delivered.", nil, nil)
+)
+
+func (c *stateCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- activeTokensDesc
+	ch <- mailPendingDesc
+}
+
+func (c *stateCollector) Collect(ch chan<- prometheus.Metric) {
+	counts, err := c.models.Token.CountActive(context.Background())
+	if err != nil {
+		c.logger.Error(err.Error())
+	} else {
+		for scope, count := range counts {
+			ch <- prometheus.MustNewConstMetric(activeTokensDesc, prometheus.GaugeValue, float64(count), scope)
+		}
+	}
+
+	pending, err := c.queue.Pending(context.Background())
+	if err != nil {
+		c.logger.Error(err.Error())
+	} else {
+		ch <- prometheus.MustNewConstMetric(mailPendingDesc, prometheus.GaugeValue, float64(pending))
+	}
+}
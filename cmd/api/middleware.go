@@ -1,20 +1,169 @@
 package main
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
+	"fmt"
 	"net/http"
+	"runtime/debug"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/sulavmhrzn/projectideas/internal/data"
+	"github.com/sulavmhrzn/projectideas/internal/i18n"
+	"go.opentelemetry.io/otel/attribute"
 )
 
+// recoverPanic turns a panic in a handler into a 500 response and closes the
+// connection, which may be left in an inconsistent state. A panic after the
+// response was started is only logged.
+func (app *application) recoverPanic(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rw := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		defer func() {
+			p := recover()
+			if p == nil {
+				return
+			}
+			if p == http.ErrAbortHandler {
+				panic(p)
+			}
+			attrs := []any{
+				"request_id", app.contextGetRequestID(r),
+				"method", r.Method,
+				"uri", r.URL.RequestURI(),
+				"stack", string(debug.Stack()),
+			}
+			if id := traceID(r); id != "" {
+				attrs = append(attrs, "trace_id", id)
+			}
+			app.logger.Error(fmt.Sprintf("panic: %v", p), attrs...)
+			if rw.wroteHeader {
+				return
+			}
+			w.Header().Set("Connection", "close")
+			app.errorResponse(w, r, http.StatusInternalServerError, codeServerError, i18n.Translate(app.language(r), codeServerError))
+		}()
+		next.ServeHTTP(rw, r)
+	})
+}
+
+// requestIDMiddleware reuses the caller's X-Request-ID when it looks sane and
+// generates one otherwise. The id is echoed back in the response.
+func (app *application) requestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get("X-Request-ID")
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		w.Header().Set("X-Request-ID", id)
+		app.traceAttributes(r, attribute.String("http.request_id", id))
+		r = app.contextSetRequestMetadata(r, &requestMetadata{id: id})
+		next.ServeHTTP(w, r)
+	})
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for _, c := range id {
+		if c < '!' || c > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// responseRecorder remembers the status code and body size written through
+// it for the access log.
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	bytes       int
+	wroteHeader bool
+}
+
+func (rw *responseRecorder) WriteHeader(status int) {
+	if !rw.wroteHeader {
+		rw.status = status
+		rw.wroteHeader = true
+	}
+	rw.ResponseWriter.WriteHeader(status)
+}
+
+func (rw *responseRecorder) Write(b []byte) (int, error) {
+	if !rw.wroteHeader {
+		rw.WriteHeader(http.StatusOK)
+	}
+	n, err := rw.ResponseWriter.Write(b)
+	rw.bytes += n
+	return n, err
+}
+
+func (rw *responseRecorder) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
 func (app *application) logRequestMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		app.infoLog.Printf("%s %s %s", r.Method, r.RemoteAddr, r.RequestURI)
+		start := time.Now()
+		rw := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rw, r)
+
+		attrs := []any{
+			"request_id", app.contextGetRequestID(r),
+			"method", r.Method,
+			"uri", r.URL.RequestURI(),
+			"remote_addr", r.RemoteAddr,
+			"status", rw.status,
+			"bytes", rw.bytes,
+			"latency_ms", float64(time.Since(start).Microseconds()) / 1000,
+		}
+		if meta := app.contextGetRequestMetadata(r); meta != nil && meta.userId != 0 {
+			attrs = append(attrs, "user_id", meta.userId)
+		}
+		if id := traceID(r); id != "" {
+			attrs = append(attrs, "trace_id", id)
+		}
+		app.logger.Info("request", attrs...)
+	})
+}
+
+// routeMiddleware records the pattern a handler was registered with, which
+// httprouter does not expose, so requests can be grouped by route.
+func (app *application) routeMiddleware(pattern string, next http.HandlerFunc) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if meta := app.contextGetRequestMetadata(r); meta != nil {
+			meta.route = pattern
+		}
+		app.nameSpan(r, pattern)
 		next.ServeHTTP(w, r)
 	})
 }
 
+func (app *application) metricsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rw := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rw, r)
+
+		route := "unmatched"
+		if meta := app.contextGetRequestMetadata(r); meta != nil && meta.route != "" {
+			route = meta.route
+		}
+		app.metrics.observeRequest(r.Method, route, rw.status, time.Since(start))
+	})
+}
+
 func (app *application) requireLoginMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		authorizationHeader := r.Header.Get("Authorization")
@@ -29,7 +178,7 @@ func (app *application) requireLoginMiddleware(next http.HandlerFunc) http.Handl
 			return
 		}
 		token := format[1]
-		user, err := app.models.User.GetForToken(token, data.ScopeAuthentication)
+		user, err := app.models.User.GetForToken(r.Context(), token, data.ScopeAuthentication)
 		if err != nil {
 			switch {
 			case errors.Is(err, data.ErrNoRows):
@@ -40,6 +189,11 @@ func (app *application) requireLoginMiddleware(next http.HandlerFunc) http.Handl
 			return
 		}
 		r = app.contextSetUser(r, user)
+		if meta := app.contextGetRequestMetadata(r); meta != nil {
+			if !app.allow(w, r, "user:"+strconv.Itoa(user.Id), meta.route) {
+				return
+			}
+		}
 		next.ServeHTTP(w, r)
 	})
 }
@@ -55,3 +209,61 @@ func (app *application) requireAuthenticatedUser(next http.HandlerFunc) http.Han
 	})
 	return app.requireLoginMiddleware(fn)
 }
+
+// limitBody caps the size of the request body read by next at maxBytes.
+func (app *application) limitBody(maxBytes int64, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+		next.ServeHTTP(w, r)
+	}
+}
+
+// corsExposedHeaders are the response headers browsers let cross-origin
+// scripts read besides the CORS-safelisted ones.
+var corsExposedHeaders = []string{
+	"Content-Disposition",
+	"X-Request-ID",
+	"RateLimit-Limit",
+	"RateLimit-Remaining",
+	"RateLimit-Reset",
+	"RateLimit-Policy",
+	"Retry-After",
+}
+
+// enableCORS allows requests from the trusted origins. Preflight requests are
+// answered by preflightHandler through httprouter, which knows the methods
+// registered for each path.
+func (app *application) enableCORS(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Origin")
+		w.Header().Add("Vary", "Access-Control-Request-Method")
+
+		origin := r.Header.Get("Origin")
+		if origin != "" && app.trustedOrigin(origin) {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Set("Access-Control-Expose-Headers", strings.Join(corsExposedHeaders, ", "))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (app *application) trustedOrigin(origin string) bool {
+	for _, trusted := range app.cfg.cors.trustedOrigins {
+		if trusted == "*" || trusted == origin {
+			return true
+		}
+	}
+	return false
+}
+
+// preflightHandler answers OPTIONS requests for registered paths. httprouter
+// has already set the Allow header to the methods the path supports.
+func (app *application) preflightHandler(w http.ResponseWriter, r *http.Request) {
+	isPreflight := r.Header.Get("Access-Control-Request-Method") != ""
+	if isPreflight && w.Header().Get("Access-Control-Allow-Origin") != "" {
+		w.Header().Set("Access-Control-Allow-Methods", w.Header().Get("Allow"))
+		w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type, X-Request-ID")
+		w.Header().Set("Access-Control-Max-Age", "600")
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
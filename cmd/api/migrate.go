@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/sulavmhrzn/projectideas/migrations"
+)
+
+const migrateUsage = `usage: api [flags] migrate <command>
+
+commands:
+  up            apply all pending migrations
+  down [n]      revert the last n migrations (default 1)
+  status        list migrations and when they were applied
+  create <name> add empty up and down files to the migrations directory`
+
+// runMigrate carries out the migrate subcommand with the arguments following
+// it on the command line.
+func (app *application) runMigrate(args []string) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+	if args[0] == "create" {
+		if len(args) != 2 {
+			return errors.New(migrateUsage)
+		}
+		paths, err := migrations.Create("migrations", args[1])
+		for _, path := range paths {
+			fmt.Println("created", path)
+		}
+		return err
+	}
+
+	db, err := openDB(app.cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	migrator, err := migrations.New(db, app.logger)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+
+	switch args[0] {
+	case "up":
+		n, err := migrator.Up(ctx)
+		if err != nil {
+			return err
+		}
+		app.logger.Info("migrations applied", "count", n)
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				return fmt.Errorf("invalid number of steps %q", args[1])
+			}
+		}
+		n, err := migrator.Down(ctx, steps)
+		if err != nil {
+			return err
+		}
+		app.logger.Info("migrations reverted", "count", n)
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "VERSION\tNAME\tAPPLIED")
+		for _, s := range statuses {
+			applied := "pending"
+			switch {
+			case s.AppliedAt != nil:
+				applied = s.AppliedAt.Format(time.RFC3339)
+			case s.Applied:
+				applied = "applied"
+			}
+			fmt.Fprintf(tw, "%06d\t%s\t%s\n", s.Version, s.Name, applied)
+		}
+		return tw.Flush()
+	default:
+		return errors.New(migrateUsage)
+	}
+	return nil
+}
+
+// autoMigrate applies pending migrations before the server starts.
+func autoMigrate(db *sql.DB, logger *slog.Logger) error {
+	migrator, err := migrations.New(db, logger)
+	if err != nil {
+		return err
+	}
+	n, err := migrator.Up(context.Background())
+	if err != nil {
+		return err
+	}
+	logger.Info("migrations applied", "count", n)
+	return nil
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/sulavmhrzn/projectideas/internal/data"
+	"github.com/sulavmhrzn/projectideas/internal/validator"
+)
+
+// notify records a notification for userId. Failing to notify must not fail
+// the request that triggered it, so errors are only logged.
+func (app *application) notify(r *http.Request, userId int, event string, actorId, ideaId int) {
+	if userId == actorId {
+		return
+	}
+	_, err := app.models.Notification.Insert(r.Context(), &data.Notification{
+		UserId:  userId,
+		ActorId: actorId,
+		IdeaId:  ideaId,
+		Event:   event,
+	})
+	if err != nil {
+		app.logError(r, err)
+	}
+}
+
+func (app *application) listNotificationsHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+	qs := r.URL.Query()
+
+	v := validator.New()
+	unreadOnly := app.readBool(qs, "unread", false, v)
+	limit := app.readInt(qs, "limit", 20, v)
+	v.Check(validator.Between(limit, 1, 100), "limit", validator.OutOfRange, 1, 100)
+
+	var cursor *data.Cursor
+	if s := app.readString(qs, "cursor", ""); s != "" {
+		var err error
+		cursor, err = data.DecodeCursor(s)
+		v.Check(err == nil, "cursor", validator.InvalidCursor)
+	}
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	notifications, err := app.models.Notification.List(r.Context(), user.Id, unreadOnly, cursor, limit)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	var nextCursor string
+	if len(notifications) == limit {
+		last := notifications[len(notifications)-1]
+		nextCursor = data.Cursor{CreatedAt: last.CreatedAt, Id: last.Id}.Encode()
+	}
+	err = app.writeJSON(w, http.StatusOK, map[string]any{"notifications": notifications, "next_cursor": nextCursor})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) markNotificationsReadHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	var input struct {
+		Ids []int `json:"ids"`
+	}
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	updated, err := app.models.Notification.MarkRead(r.Context(), user.Id, input.Ids)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, map[string]any{"updated": updated})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) showNotificationPreferencesHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	preferences, err := app.models.Notification.Preferences(r.Context(), user.Id)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, map[string]any{"preferences": preferences})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) updateNotificationPreferencesHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	var input map[string]bool
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	var unknown []string
+	for event := range input {
+		if !validator.In(event, data.Events...) {
+			unknown = append(unknown, event)
+		}
+	}
+	sort.Strings(unknown)
+	v := validator.New()
+	v.Check(len(unknown) == 0, "preferences", validator.UnknownEvent, strings.Join(unknown, ", "))
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Notification.SetPreferences(r.Context(), user.Id, input)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	preferences, err := app.models.Notification.Preferences(r.Context(), user.Id)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, map[string]any{"preferences": preferences})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNotifications(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.router())
+	alice := ts.register(t, "alice", "alice@example.com")
+	bob := ts.register(t, "bob", "bob@example.com")
+	carol := ts.register(t, "carol", "carol@example.com")
+	aliceId, bobId := userId(t, app, "alice@example.com"), userId(t, app, "bob@example.com")
+	aliceFollow := fmt.Sprintf("/v1/profiles/%d/follow", aliceId)
+
+	for _, token := range []string{bob, carol, bob} {
+		status, body := ts.do(t, http.MethodPost, aliceFollow, token, nil)
+		if status != http.StatusOK {
+			t.Fatalf("follow: got status %d: %v", status, body)
+		}
+	}
+
+	status, body := ts.do(t, http.MethodGet, "/v1/notifications", alice, nil)
+	if status != http.StatusOK {
+		t.Fatalf("list: got status %d: %v", status, body)
+	}
+	notifications := body["notifications"].([]any)
+	if len(notifications) != 2 {
+		t.Fatalf("got %d notifications, want one per new follower: %v", len(notifications), notifications)
+	}
+	latest := notifications[0].(map[string]any)
+	if latest["event"] != "follow" || latest["actor_id"] != float64(userId(t, app, "carol@example.com")) || latest["read_at"] != nil {
+		t.Errorf("got latest notification %v, want an unread follow by carol", latest)
+	}
+
+	status, body = ts.do(t, http.MethodPost, "/v1/notifications/read", alice, map[string]any{"ids": []any{latest["id"]}})
+	if status != http.StatusOK || body["updated"] != 1.0 {
+		t.Errorf("mark one read: got status %d: %v", status, body)
+	}
+	status, body = ts.do(t, http.MethodGet, "/v1/notifications?unread=true", alice, nil)
+	if status != http.StatusOK {
+		t.Fatalf("list unread: got status %d: %v", status, body)
+	}
+	if unread := body["notifications"].([]any); len(unread) != 1 || unread[0].(map[string]any)["actor_id"] != float64(bobId) {
+		t.Errorf("got unread %v, want the follow by bob", unread)
+	}
+	status, body = ts.do(t, http.MethodPost, "/v1/notifications/read", alice, map[string]any{})
+	if status != http.StatusOK || body["updated"] != 1.0 {
+		t.Errorf("mark all read: got status %d: %v", status, body)
+	}
+	status, body = ts.do(t, http.MethodGet, "/v1/notifications?unread=true", bob, nil)
+	if status != http.StatusOK || len(body["notifications"].([]any)) != 0 {
+		t.Errorf("another user's notifications: got status %d: %v", status, body)
+	}
+
+	// Turning an event off stops new notifications for it.
+	status, body = ts.do(t, http.MethodPut, "/v1/notifications/preferences", alice, map[string]bool{"follow": false})
+	if status != http.StatusOK || body["preferences"].(map[string]any)["follow"] != false {
+		t.Fatalf("update preferences: got status %d: %v", status, body)
+	}
+	dave := ts.register(t, "dave", "dave@example.com")
+	status, _ = ts.do(t, http.MethodPost, aliceFollow, dave, nil)
+	if status != http.StatusOK {
+		t.Fatalf("follow: got status %d", status)
+	}
+	status, body = ts.do(t, http.MethodGet, "/v1/notifications?unread=true", alice, nil)
+	if status != http.StatusOK || len(body["notifications"].([]any)) != 0 {
+		t.Errorf("notified of a disabled event: got status %d: %v", status, body)
+	}
+}
+
+func TestUpdatePreferencesUnknownEvent(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.router())
+	token := ts.register(t, "alice", "alice@example.com")
+
+	req, err := http.NewRequest(http.MethodPut, ts.URL+"/v1/notifications/preferences", strings.NewReader(`{"vote": false, "follow": false, "comment": true}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Accept-Language", "fr")
+	res, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusBadRequest {
+		t.Fatalf("got status %d, want %d", res.StatusCode, http.StatusBadRequest)
+	}
+	var body map[string]any
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{"preferences": []any{"contient des événements de notification inconnus : comment, vote"}}
+	if !reflect.DeepEqual(body["error"], want) {
+		t.Errorf("got error %v, want %v", body["error"], want)
+	}
+
+	status, body := ts.do(t, http.MethodGet, "/v1/notifications/preferences", token, nil)
+	if status != http.StatusOK {
+		t.Fatalf("got status %d", status)
+	}
+	if got := body["preferences"].(map[string]any)["follow"]; got != true {
+		t.Errorf("a rejected update changed the follow preference to %v", got)
+	}
+}
+
+func TestInvalidCursor(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.router())
+	token := ts.register(t, "alice", "alice@example.com")
+
+	for _, path := range []string{"/v1/feed", "/v1/notifications"} {
+		status, body := ts.do(t, http.MethodGet, path+"?cursor=garbage", token, nil)
+		if status != http.StatusBadRequest {
+			t.Errorf("%s: got status %d, want %d", path, status, http.StatusBadRequest)
+		}
+		want := map[string]any{"cursor": []any{"must be a valid cursor"}}
+		if !reflect.DeepEqual(body["error"], want) {
+			t.Errorf("%s: got error %v, want %v", path, body["error"], want)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var routeParam = regexp.MustCompile(`:(\w+)`)
+
+// TestOpenAPICoverage checks that openapi.json documents exactly the routes
+// served by router, including the development only ones.
+func TestOpenAPICoverage(t *testing.T) {
+	var spec struct {
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(openAPISpec, &spec); err != nil {
+		t.Fatal(err)
+	}
+	documented := make(map[string]bool)
+	for path, item := range spec.Paths {
+		for method := range item {
+			if method != "parameters" {
+				documented[strings.ToUpper(method)+" "+path] = true
+			}
+		}
+	}
+
+	app := newTestApplication(t)
+	app.cfg.dev = true
+	registered := make(map[string]bool)
+	for _, r := range app.routes() {
+		op := r.method + " " + routeParam.ReplaceAllString(r.pattern, "{$1}")
+		registered[op] = true
+		if !documented[op] {
+			t.Errorf("%s is registered but missing from openapi.json", op)
+		}
+	}
+	for op := range documented {
+		if !registered[op] {
+			t.Errorf("%s is documented in openapi.json but not registered", op)
+		}
+	}
+}
+
+func TestOpenAPIHandler(t *testing.T) {
+	ts := newTestServer(t, newTestApplication(t).router())
+
+	status, body := ts.do(t, http.MethodGet, "/v1/openapi.json", "", nil)
+	if status != http.StatusOK {
+		t.Fatalf("got status %d, want %d", status, http.StatusOK)
+	}
+	if body["openapi"] != "3.1.0" {
+		t.Errorf("got openapi version %v, want 3.1.0", body["openapi"])
+	}
+}
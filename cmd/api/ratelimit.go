@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sulavmhrzn/projectideas/internal/ratelimit"
+)
+
+// rateLimit throttles requests to the route registered as pattern by client
+// IP. Authenticated users are throttled by requireLoginMiddleware as well.
+func (app *application) rateLimit(pattern string, next http.HandlerFunc) http.HandlerFunc {
+	if !app.cfg.limiter.enabled {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !app.allow(w, r, "ip:"+app.clientIP(r), pattern) {
+			return
+		}
+		next.ServeHTTP(w, r)
+	}
+}
+
+// allow takes a token from the bucket of client for the route pattern, writes
+// the RateLimit headers and responds with 429 when the bucket is empty.
+// Routes without a limit of their own share one bucket per client. Limiting
+// fails open: if the store errors the request is let through.
+func (app *application) allow(w http.ResponseWriter, r *http.Request, client, pattern string) bool {
+	if !app.cfg.limiter.enabled {
+		return true
+	}
+	key := client
+	limit, ok := app.cfg.limiter.routes[pattern]
+	if ok {
+		key += ":" + pattern
+	} else {
+		limit = app.cfg.limiter.defaultLimit
+	}
+
+	res, err := app.limiter.Take(r.Context(), key, limit)
+	if err != nil {
+		app.logError(r, err)
+		return true
+	}
+	window := int(float64(limit.Burst) / limit.Rate)
+	w.Header().Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", limit.Burst, window))
+	w.Header().Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+	w.Header().Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+	w.Header().Set("RateLimit-Reset", strconv.Itoa(int(res.Reset.Seconds())))
+	if !res.Allowed {
+		w.Header().Set("Retry-After", strconv.Itoa(int(res.RetryAfter.Seconds())))
+		app.rateLimitExceededResponse(w, r)
+		return false
+	}
+	return true
+}
+
+// clientIP returns the address of the client. X-Forwarded-For is only
+// trusted when the request comes from a trusted proxy, in which case the
+// rightmost address that is not a trusted proxy is used.
+func (app *application) clientIP(r *http.Request) string {
+	addrPort, err := netip.ParseAddrPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	ip := addrPort.Addr().Unmap()
+	if !app.trustedProxy(ip) {
+		return ip.String()
+	}
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			break
+		}
+		ip = hop.Unmap()
+		if !app.trustedProxy(ip) {
+			break
+		}
+	}
+	return ip.String()
+}
+
+func (app *application) trustedProxy(ip netip.Addr) bool {
+	for _, prefix := range app.cfg.limiter.trustedProxies {
+		if prefix.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseTrustedProxies parses a comma separated list of addresses and CIDR
+// ranges.
+func parseTrustedProxies(s string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, field := range strings.Split(s, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		if !strings.Contains(field, "/") {
+			addr, err := netip.ParseAddr(field)
+			if err != nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q", field)
+			}
+			prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(field)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q", field)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+// parseRouteLimits parses a comma separated list of <route>=<limit>, such as
+// "/v1/users/register=5/1h".
+func parseRouteLimits(s string) (map[string]ratelimit.Limit, error) {
+	limits := make(map[string]ratelimit.Limit)
+	for _, field := range strings.Split(s, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		route, value, ok := strings.Cut(field, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid route limit %q: want <route>=<limit>", field)
+		}
+		limit, err := ratelimit.ParseLimit(value)
+		if err != nil {
+			return nil, err
+		}
+		limits[route] = limit
+	}
+	return limits, nil
+}
+
+// longestLimitPeriod is the longest time any configured limit takes to refill.
+// Buckets unused for longer are full again and can be forgotten.
+func longestLimitPeriod(cfg config) time.Duration {
+	longest := cfg.limiter.defaultLimit.Period()
+	for _, limit := range cfg.limiter.routes {
+		longest = max(longest, limit.Period())
+	}
+	return longest
+}
+
+func (app *application) deleteStaleRateLimitsJob() {
+	store, ok := app.limiter.(*ratelimit.PostgresStore)
+	if !ok {
+		return
+	}
+	deleted, err := store.DeleteStale(context.Background(), longestLimitPeriod(app.cfg))
+	if err != nil {
+		app.logJobError(err)
+		return
+	}
+	if deleted > 0 {
+		app.logger.Info("deleted stale rate limit buckets", "count", deleted)
+	}
+}
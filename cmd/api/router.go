@@ -6,18 +6,75 @@ import (
 	"github.com/julienschmidt/httprouter"
 )
 
+// route is a method and path pattern registered with httprouter, with the
+// handler serving it and the largest body it accepts, or 0 for the configured
+// default.
+type route struct {
+	method       string
+	pattern      string
+	maxBodyBytes int64
+	handler      http.HandlerFunc
+}
+
+// smallBodyBytes limits the body of routes that only accept a few short fields,
+// such as credentials.
+const smallBodyBytes = 4 << 10
+
+// routes lists every route the api serves.
+func (app *application) routes() []route {
+	routes := []route{
+		{http.MethodGet, "/v1/ping", 0, app.pingHandler},
+		{http.MethodGet, "/v1/openapi.json", 0, app.openAPIHandler},
+		{http.MethodGet, "/v1/docs", 0, app.docsHandler},
+		{http.MethodPost, "/v1/users/register", smallBodyBytes, app.createUserHandler},
+		{http.MethodPost, "/v1/users/sendResetPassword", smallBodyBytes, app.sendResetPasswordTokenHandler},
+		{http.MethodPut, "/v1/users/resetPassword", smallBodyBytes, app.resetPasswordHandler},
+		{http.MethodGet, "/v1/users/me/export", 0, app.requireAuthenticatedUser(app.exportUserDataHandler)},
+		{http.MethodDelete, "/v1/users/me", smallBodyBytes, app.requireAuthenticatedUser(app.deleteUserHandler)},
+		{http.MethodDelete, "/v1/users/me/deletion", 0, app.requireAuthenticatedUser(app.cancelUserDeletionHandler)},
+		{http.MethodPut, "/v1/users/me/digest", smallBodyBytes, app.requireAuthenticatedUser(app.updateDigestFrequencyHandler)},
+		{http.MethodGet, "/v1/users/digest/unsubscribe", 0, app.unsubscribeDigestHandler},
+		{http.MethodPost, "/v1/users/digest/unsubscribe", smallBodyBytes, app.unsubscribeDigestHandler},
+		{http.MethodPost, "/v1/tokens/authentication", smallBodyBytes, app.generateTokenHandler},
+		{http.MethodPost, "/v1/ideas", 0, app.requireAuthenticatedUser(app.createIdeaHandler)},
+		{http.MethodGet, "/v1/ideas", 0, app.listIdeasHandler},
+		{http.MethodGet, "/v1/ideas/:id", 0, app.getIdeaHandler},
+		{http.MethodDelete, "/v1/ideas/:id", 0, app.requireAuthenticatedUser(app.deleteIdeaHandler)},
+		{http.MethodPut, "/v1/ideas/:id", 0, app.requireAuthenticatedUser(app.updateIdeaHandler)},
+		{http.MethodGet, "/v1/profiles/:id", 0, app.showProfileHandler},
+		{http.MethodGet, "/v1/profiles/:id/followers", 0, app.listFollowersHandler},
+		{http.MethodGet, "/v1/profiles/:id/following", 0, app.listFollowingHandler},
+		{http.MethodPost, "/v1/profiles/:id/follow", 0, app.requireAuthenticatedUser(app.followUserHandler)},
+		{http.MethodDelete, "/v1/profiles/:id/follow", 0, app.requireAuthenticatedUser(app.unfollowUserHandler)},
+		{http.MethodPost, "/v1/tags/:title/follow", 0, app.requireAuthenticatedUser(app.followTagHandler)},
+		{http.MethodDelete, "/v1/tags/:title/follow", 0, app.requireAuthenticatedUser(app.unfollowTagHandler)},
+		{http.MethodGet, "/v1/feed", 0, app.requireAuthenticatedUser(app.feedHandler)},
+		{http.MethodGet, "/v1/notifications", 0, app.requireAuthenticatedUser(app.listNotificationsHandler)},
+		{http.MethodPost, "/v1/notifications/read", 0, app.requireAuthenticatedUser(app.markNotificationsReadHandler)},
+		{http.MethodGet, "/v1/notifications/preferences", 0, app.requireAuthenticatedUser(app.showNotificationPreferencesHandler)},
+		{http.MethodPut, "/v1/notifications/preferences", smallBodyBytes, app.requireAuthenticatedUser(app.updateNotificationPreferencesHandler)},
+	}
+	if app.cfg.dev {
+		routes = append(routes, route{http.MethodGet, "/debug/mail", 0, app.listMailHandler})
+	}
+	return append(routes, route{http.MethodGet, "/metrics", 0, app.metrics.handler().ServeHTTP})
+}
+
 func (app *application) router() http.Handler {
 	mux := httprouter.New()
-	mux.HandlerFunc(http.MethodGet, "/v1/ping", app.pingHandler)
-	mux.HandlerFunc(http.MethodPost, "/v1/users/register", app.createUserHandler)
-	mux.HandlerFunc(http.MethodPost, "/v1/users/sendResetPassword", app.sendResetPasswordTokenHandler)
-	mux.HandlerFunc(http.MethodPut, "/v1/users/resetPassword", app.resetPasswordHandler)
-	mux.HandlerFunc(http.MethodPost, "/v1/tokens/authentication", app.generateTokenHandler)
-	mux.HandlerFunc(http.MethodPost, "/v1/ideas", app.requireAuthenticatedUser(app.createIdeaHandler))
-	mux.HandlerFunc(http.MethodGet, "/v1/ideas", app.listIdeasHandler)
-	mux.HandlerFunc(http.MethodGet, "/v1/ideas/:id", app.getIdeaHandler)
-	mux.HandlerFunc(http.MethodDelete, "/v1/ideas/:id", app.requireAuthenticatedUser(app.deleteIdeaHandler))
-	mux.HandlerFunc(http.MethodPut, "/v1/ideas/:id", app.requireAuthenticatedUser(app.updateIdeaHandler))
+	mux.NotFound = http.HandlerFunc(app.notFoundResponse)
+	mux.MethodNotAllowed = http.HandlerFunc(app.methodNotAllowedResponse)
+	mux.GlobalOPTIONS = http.HandlerFunc(app.preflightHandler)
+	for _, rt := range app.routes() {
+		maxBodyBytes := rt.maxBodyBytes
+		if maxBodyBytes == 0 {
+			maxBodyBytes = app.cfg.server.maxBodyBytes
+		}
+		mux.HandlerFunc(rt.method, rt.pattern, app.routeMiddleware(rt.pattern, app.rateLimit(rt.pattern, app.limitBody(maxBodyBytes, rt.handler))))
+	}
 
-	return app.logRequestMiddleware(mux)
+	// The inner recoverPanic answers handler panics while the request is still
+	// logged, counted and given CORS headers; the outer one catches panics in
+	// the middleware itself.
+	return app.recoverPanic(app.tracingMiddleware(app.requestIDMiddleware(app.logRequestMiddleware(app.metricsMiddleware(app.enableCORS(app.recoverPanic(mux)))))))
 }
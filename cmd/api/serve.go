@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"runtime/debug"
+	"syscall"
+)
+
+// serve runs the HTTP server until SIGINT or SIGTERM. It then stops accepting
+// connections, waits for in-flight requests and background tasks, drains the
+// mail queue and flushes traces, all within cfg.shutdownTimeout.
+func (app *application) serve() error {
+	server := &http.Server{
+		Addr:              fmt.Sprintf(":%d", app.cfg.port),
+		Handler:           app.router(),
+		ReadHeaderTimeout: app.cfg.server.readHeaderTimeout,
+		ReadTimeout:       app.cfg.server.readTimeout,
+		WriteTimeout:      app.cfg.server.writeTimeout,
+		IdleTimeout:       app.cfg.server.idleTimeout,
+		MaxHeaderBytes:    app.cfg.server.maxHeaderBytes,
+		ErrorLog:          slog.NewLogLogger(app.logger.Handler(), slog.LevelWarn),
+	}
+
+	shutdownErr := make(chan error)
+	go func() {
+		quit := make(chan os.Signal, 1)
+		signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+		s := <-quit
+		app.logger.Info("shutting down server", "signal", s.String())
+
+		ctx, cancel := context.WithTimeout(context.Background(), app.cfg.shutdownTimeout)
+		defer cancel()
+		shutdownErr <- app.shutdown(ctx, server)
+	}()
+
+	app.logger.Info("server running", "addr", server.Addr)
+	err := server.ListenAndServe()
+	if !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	err = <-shutdownErr
+	if err != nil {
+		return err
+	}
+	app.logger.Info("server stopped", "addr", server.Addr)
+	return nil
+}
+
+func (app *application) shutdown(ctx context.Context, server *http.Server) error {
+	err := server.Shutdown(ctx)
+	if err != nil {
+		return err
+	}
+	close(app.stop)
+
+	app.logger.Info("draining mail queue")
+	err = app.mailQueue.Shutdown(ctx)
+	if err != nil {
+		return err
+	}
+
+	app.logger.Info("waiting for background tasks")
+	done := make(chan struct{})
+	go func() {
+		app.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return fmt.Errorf("background tasks: %w", ctx.Err())
+	}
+
+	return app.shutdownTracing(ctx)
+}
+
+// background runs fn in a goroutine that serve waits for before exiting. A
+// panic in fn is logged instead of crashing the process.
+func (app *application) background(fn func()) {
+	app.wg.Add(1)
+	go func() {
+		defer app.wg.Done()
+		defer func() {
+			if p := recover(); p != nil {
+				app.logger.Error(fmt.Sprintf("panic in background task: %v", p), "stack", string(debug.Stack()))
+			}
+		}()
+		fn()
+	}()
+}
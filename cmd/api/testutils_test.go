@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sulavmhrzn/projectideas/internal/data/memory"
+	"github.com/sulavmhrzn/projectideas/internal/mailer"
+)
+
+type testServer struct {
+	*httptest.Server
+}
+
+// newTestApplication returns an application backed by the in-memory stores.
+// Its mail queue delivers to app.mailCatcher.
+func newTestApplication(t *testing.T) *application {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	app := &application{
+		logger:      logger,
+		models:      memory.NewModel(),
+		metrics:     newMetrics(),
+		mailCatcher: &mailer.Recorder{},
+	}
+	app.cfg.server.maxBodyBytes = 1 << 20
+	app.mailQueue = mailer.NewQueue(mailer.NewMemoryStore(), app.mailCatcher, logger)
+	go app.mailQueue.Run()
+	t.Cleanup(func() { app.mailQueue.Shutdown(context.Background()) })
+	return app
+}
+
+// waitForMail waits for the mail queue to deliver n messages in total and
+// returns them.
+func waitForMail(t *testing.T, app *application, n int) []mailer.Message {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		messages := app.mailCatcher.Messages()
+		if len(messages) >= n {
+			return messages
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("got %d messages, want %d", len(messages), n)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func newTestServer(t *testing.T, h http.Handler) *testServer {
+	ts := httptest.NewServer(h)
+	t.Cleanup(ts.Close)
+	return &testServer{ts}
+}
+
+// doRaw sends body as is and returns the status code and the decoded response.
+func (ts *testServer) doRaw(t *testing.T, method, path, token string, body []byte) (int, map[string]any) {
+	t.Helper()
+
+	req, err := http.NewRequest(method, ts.URL+path, bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	res, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	var decoded map[string]any
+	raw, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(raw) > 0 && raw[0] == '{' {
+		if err := json.Unmarshal(raw, &decoded); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return res.StatusCode, decoded
+}
+
+// do sends body encoded as JSON, authenticating with token when it is not
+// empty, and returns the status code along with the decoded response.
+func (ts *testServer) do(t *testing.T, method, path, token string, body any) (int, map[string]any) {
+	t.Helper()
+
+	var payload []byte
+	if body != nil {
+		var err error
+		payload, err = json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return ts.doRaw(t, method, path, token, payload)
+}
+
+// register creates a user and returns an authentication token for it.
+func (ts *testServer) register(t *testing.T, username, email string) string {
+	t.Helper()
+
+	password := "pa55word1234"
+	status, _ := ts.do(t, http.MethodPost, "/v1/users/register", "", map[string]string{
+		"username": username,
+		"email":    email,
+		"password": password,
+	})
+	if status != http.StatusOK {
+		t.Fatalf("register %s: got status %d", username, status)
+	}
+	status, body := ts.do(t, http.MethodPost, "/v1/tokens/authentication", "", map[string]string{
+		"email":    email,
+		"password": password,
+	})
+	if status != http.StatusOK {
+		t.Fatalf("login %s: got status %d", username, status)
+	}
+	return body["token"].(string)
+}
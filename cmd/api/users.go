@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net/http"
@@ -40,7 +41,7 @@ func (app *application) createUserHandler(w http.ResponseWriter, r *http.Request
 		app.serverErrorResponse(w, r, err)
 		return
 	}
-	user, err = app.models.User.Insert(user)
+	user, err = app.models.User.Insert(r.Context(), user)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrDuplicateEmail) || errors.Is(err, data.ErrDuplicateUsername):
@@ -58,8 +59,8 @@ func (app *application) createUserHandler(w http.ResponseWriter, r *http.Request
 
 func (app *application) generateTokenHandler(w http.ResponseWriter, r *http.Request) {
 	var input struct {
-		Email    string `json:"email"`
-		Password string `json:"password"`
+		Email    string `json:"email" validate:"required,email"`
+		Password string `json:"password" validate:"required,minrunes=11"`
 	}
 	err := app.readJSON(w, r, &input)
 	if err != nil {
@@ -67,16 +68,13 @@ func (app *application) generateTokenHandler(w http.ResponseWriter, r *http.Requ
 		return
 	}
 	v := validator.New()
-	v.Check(input.Email != "", "email", "must be provided")
-	v.Check(validator.ValidEmail(input.Email), "email", "must be a valid email address")
-	v.Check(input.Password != "", "password", "must be provided")
-	v.Check(len(input.Password) < 72, "password", "must not be greater than 72 characters long")
-	v.Check(len(input.Password) > 10, "password", "must be greater than 10 characters long")
+	v.Struct(&input)
+	v.Check(len(input.Password) < 72, "password", validator.TooManyBytes, 71)
 	if !v.Valid() {
 		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
-	user, err := app.models.User.GetByEmail(input.Email)
+	user, err := app.models.User.GetByEmail(r.Context(), input.Email)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrNoRows):
@@ -87,11 +85,11 @@ func (app *application) generateTokenHandler(w http.ResponseWriter, r *http.Requ
 		return
 	}
 	ok := user.Password.Compare(input.Password)
-	if !ok {
+	if !ok || user.Banned() {
 		app.invalidCredentialsResponse(w, r)
 		return
 	}
-	token, err := app.models.Token.New(user.Id, 1*time.Hour, data.ScopeAuthentication)
+	token, err := app.models.Token.New(r.Context(), user.Id, 1*time.Hour, data.ScopeAuthentication)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -112,7 +110,7 @@ func (app *application) sendResetPasswordTokenHandler(w http.ResponseWriter, r *
 		app.badRequestResponse(w, r, err)
 		return
 	}
-	user, err := app.models.User.GetByEmail(input.Email)
+	user, err := app.models.User.GetByEmail(r.Context(), input.Email)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrNoRows):
@@ -122,33 +120,41 @@ func (app *application) sendResetPasswordTokenHandler(w http.ResponseWriter, r *
 		}
 		return
 	}
-	token, err := app.models.Token.New(user.Id, 24*time.Hour, data.ScopePasswordReset)
+	err = app.sendResetPasswordEmail(r.Context(), user)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
-	go func() {
-		dialer := mailer.NewDialer(app.cfg.mailer.host, app.cfg.mailer.port, app.cfg.mailer.username, app.cfg.mailer.password)
-		err = mailer.SendMail(dialer,
-			app.cfg.mailer.EmailFrom,
-			user.Email,
-			"Reset Password",
-			fmt.Sprintf("Your reset token %q. Please make an request to /v1/users/resetPassword with this token", token.Token),
-		)
-		if err != nil {
-			app.logError(err)
-		}
-	}()
 	err = app.writeJSON(w, http.StatusOK, map[string]any{"message": "reset password token sent"})
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
 }
 
+// sendResetPasswordEmail queues an email with a new password reset token for
+// user.
+func (app *application) sendResetPasswordEmail(ctx context.Context, user *data.User) error {
+	token, err := app.models.Token.New(ctx, user.Id, 24*time.Hour, data.ScopePasswordReset)
+	if err != nil {
+		return err
+	}
+	msg, err := mailer.Render("reset_password.tmpl", map[string]any{
+		"Username":  user.Username,
+		"Token":     token.Token,
+		"ExpiresAt": token.ExpiresAt,
+	})
+	if err != nil {
+		return err
+	}
+	msg.From = app.cfg.mailer.EmailFrom
+	msg.To = user.Email
+	return app.mailQueue.Enqueue(ctx, msg)
+}
+
 func (app *application) resetPasswordHandler(w http.ResponseWriter, r *http.Request) {
 	var input struct {
-		Token    string `json:"token"`
-		Password string `json:"password"`
+		Token    string `json:"token" validate:"required"`
+		Password string `json:"password" validate:"required,minrunes=11"`
 	}
 	err := app.readJSON(w, r, &input)
 	if err != nil {
@@ -156,14 +162,12 @@ func (app *application) resetPasswordHandler(w http.ResponseWriter, r *http.Requ
 		return
 	}
 	v := validator.New()
-	v.Check(input.Token != "", "token", "must be provided")
-	v.Check(input.Password != "", "password", "must be provided")
-	v.Check(len(input.Password) > 10, "password", "must be greater than 10 characters long")
+	v.Struct(&input)
 	if !v.Valid() {
 		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
-	user, err := app.models.User.GetForToken(input.Token, data.ScopePasswordReset)
+	user, err := app.models.User.GetForToken(r.Context(), input.Token, data.ScopePasswordReset)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrNoRows):
@@ -178,12 +182,12 @@ func (app *application) resetPasswordHandler(w http.ResponseWriter, r *http.Requ
 		app.serverErrorResponse(w, r, err)
 		return
 	}
-	err = app.models.User.UpdatePassword(user.Id, user.Password.HashedPassword)
+	err = app.models.User.UpdatePassword(r.Context(), user.Id, user.Password.HashedPassword)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
-	err = app.models.Token.DeleteForUser(user.Id)
+	err = app.models.Token.DeleteForUser(r.Context(), user.Id)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -193,3 +197,123 @@ func (app *application) resetPasswordHandler(w http.ResponseWriter, r *http.Requ
 		app.serverErrorResponse(w, r, err)
 	}
 }
+
+func (app *application) exportUserDataHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	ideas, err := app.models.Idea.ListForUser(r.Context(), user.Id)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	tokens, err := app.models.Token.ListForUser(r.Context(), user.Id)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	tags := []data.Tag{}
+	seenTags := make(map[string]bool)
+	for _, idea := range ideas {
+		for _, tag := range idea.Tags {
+			if !seenTags[tag.Title] {
+				seenTags[tag.Title] = true
+				tags = append(tags, tag)
+			}
+		}
+	}
+
+	type tokenMetadata struct {
+		Scope     string    `json:"scope"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+	tokensMetadata := []tokenMetadata{}
+	for _, token := range tokens {
+		tokensMetadata = append(tokensMetadata, tokenMetadata{Scope: token.Scope, ExpiresAt: token.ExpiresAt})
+	}
+	if ideas == nil {
+		ideas = []data.Idea{}
+	}
+
+	files := map[string]any{
+		"profile.json": user,
+		"ideas.json":   ideas,
+		"tags.json":    tags,
+		"tokens.json":  tokensMetadata,
+	}
+	err = app.writeZIP(w, http.StatusOK, fmt.Sprintf("%s-export.zip", user.Username), files)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteUserHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	var input struct {
+		Password      string `json:"password"`
+		ReassignIdeas bool   `json:"reassign_ideas"`
+	}
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	v := validator.New()
+	v.Check(input.Password != "", "password", validator.Required)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	account, err := app.models.User.GetByEmail(r.Context(), user.Email)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if !account.Password.Compare(input.Password) {
+		app.invalidCredentialsResponse(w, r)
+		return
+	}
+
+	deleteAfter := time.Now().Add(app.cfg.deletionGracePeriod)
+	err = app.models.User.ScheduleDeletion(r.Context(), user.Id, deleteAfter, input.ReassignIdeas)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	// Log out every session. The user may still log in during the grace
+	// period, but only DELETE /v1/users/me/deletion cancels the deletion.
+	err = app.models.Token.DeleteForUser(r.Context(), user.Id)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	err = app.writeJSON(w, http.StatusAccepted, map[string]any{
+		"message":        "account scheduled for deletion",
+		"delete_after":   deleteAfter,
+		"reassign_ideas": input.ReassignIdeas,
+	})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) cancelUserDeletionHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	err := app.models.User.CancelDeletion(r.Context(), user.Id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrNoRows):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, map[string]string{"message": "account deletion cancelled"})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,327 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/term"
+)
+
+type tag struct {
+	Title string `json:"title"`
+}
+
+type idea struct {
+	Id          int       `json:"id"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	Tags        []tag     `json:"tags"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+func (c *cli) login(args []string) error {
+	flags := flag.NewFlagSet("login", flag.ContinueOnError)
+	email := flags.String("email", "", "account email")
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+	if *email == "" {
+		*email = prompt("Email: ")
+	}
+	password, err := promptPassword("Password: ")
+	if err != nil {
+		return err
+	}
+
+	var token credential
+	err = c.client.do(http.MethodPost, "/v1/tokens/authentication", map[string]string{
+		"email":    *email,
+		"password": password,
+	}, &token)
+	if err != nil {
+		return err
+	}
+	credentials, err := loadCredentials()
+	if err != nil {
+		return err
+	}
+	credentials[c.client.baseURL] = token
+	err = saveCredentials(credentials)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "Logged in until %s\n", token.ExpiresAt.Local().Format(time.Kitchen))
+	return nil
+}
+
+func (c *cli) logout() error {
+	credentials, err := loadCredentials()
+	if err != nil {
+		return err
+	}
+	delete(credentials, c.client.baseURL)
+	return saveCredentials(credentials)
+}
+
+func (c *cli) listIdeas() error {
+	var raw json.RawMessage
+	err := c.client.do(http.MethodGet, "/v1/ideas", nil, &raw)
+	if err != nil {
+		return err
+	}
+	if c.format == "json" {
+		return printJSON(raw)
+	}
+	var ideas []idea
+	if err := json.Unmarshal(raw, &ideas); err != nil {
+		return err
+	}
+	rows := [][]string{{"ID", "TITLE", "TAGS", "CREATED"}}
+	for _, i := range ideas {
+		rows = append(rows, []string{strconv.Itoa(i.Id), i.Title, tagList(i.Tags), i.CreatedAt.Local().Format(time.DateOnly)})
+	}
+	return printTable(rows)
+}
+
+func (c *cli) showIdea(args []string) error {
+	id, err := ideaID(args)
+	if err != nil {
+		return err
+	}
+	var raw json.RawMessage
+	err = c.client.do(http.MethodGet, "/v1/ideas/"+id, nil, &raw)
+	if err != nil {
+		return err
+	}
+	return c.printIdea(raw)
+}
+
+func (c *cli) createIdea(args []string) error {
+	flags := flag.NewFlagSet("ideas create", flag.ContinueOnError)
+	title := flags.String("title", "", "title of the idea")
+	description := flags.String("description", "", "description of the idea")
+	tags := flags.String("tags", "", "comma separated tags")
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+	if err := c.authenticate(); err != nil {
+		return err
+	}
+
+	input := map[string]any{"title": *title, "description": *description, "tags": []tag{}}
+	for _, t := range strings.Split(*tags, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			input["tags"] = append(input["tags"].([]tag), tag{Title: t})
+		}
+	}
+	var raw json.RawMessage
+	err := c.client.do(http.MethodPost, "/v1/ideas", input, &raw)
+	if err != nil {
+		return loginHint(err)
+	}
+	return c.printIdea(raw)
+}
+
+func (c *cli) editIdea(args []string) error {
+	id, err := ideaID(args)
+	if err != nil {
+		return err
+	}
+	flags := flag.NewFlagSet("ideas edit", flag.ContinueOnError)
+	flags.String("title", "", "new title")
+	flags.String("description", "", "new description")
+	if err := flags.Parse(args[1:]); err != nil {
+		return errUsage
+	}
+	input := make(map[string]string)
+	flags.Visit(func(f *flag.Flag) { input[f.Name] = f.Value.String() })
+	if len(input) == 0 {
+		return errors.New("nothing to change, pass -title or -description")
+	}
+	if err := c.authenticate(); err != nil {
+		return err
+	}
+
+	var raw json.RawMessage
+	err = c.client.do(http.MethodPut, "/v1/ideas/"+id, input, &raw)
+	if err != nil {
+		return loginHint(err)
+	}
+	return c.printIdea(raw)
+}
+
+func (c *cli) deleteIdea(args []string) error {
+	id, err := ideaID(args)
+	if err != nil {
+		return err
+	}
+	if err := c.authenticate(); err != nil {
+		return err
+	}
+	err = c.client.do(http.MethodDelete, "/v1/ideas/"+id, nil, nil)
+	if err != nil {
+		return loginHint(err)
+	}
+	fmt.Fprintf(stdout, "Deleted idea %s\n", id)
+	return nil
+}
+
+// listTags counts the ideas using each tag. The api has no tags endpoint, so
+// they are collected from the list of ideas.
+func (c *cli) listTags() error {
+	var ideas []idea
+	err := c.client.do(http.MethodGet, "/v1/ideas", nil, &ideas)
+	if err != nil {
+		return err
+	}
+	counts := make(map[string]int)
+	for _, i := range ideas {
+		for _, t := range i.Tags {
+			counts[t.Title]++
+		}
+	}
+	type tagCount struct {
+		Title string `json:"title"`
+		Ideas int    `json:"ideas"`
+	}
+	tags := make([]tagCount, 0, len(counts))
+	for title, n := range counts {
+		tags = append(tags, tagCount{title, n})
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		if tags[i].Ideas != tags[j].Ideas {
+			return tags[i].Ideas > tags[j].Ideas
+		}
+		return tags[i].Title < tags[j].Title
+	})
+
+	if c.format == "json" {
+		return printJSON(tags)
+	}
+	rows := [][]string{{"TAG", "IDEAS"}}
+	for _, t := range tags {
+		rows = append(rows, []string{t.Title, strconv.Itoa(t.Ideas)})
+	}
+	return printTable(rows)
+}
+
+// resetPassword either requests a reset token by email or uses one to set a
+// new password.
+func (c *cli) resetPassword(args []string) error {
+	flags := flag.NewFlagSet("account reset-password", flag.ContinueOnError)
+	email := flags.String("email", "", "send a reset token to this address")
+	token := flags.String("token", "", "reset token received by email")
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+
+	var message struct {
+		Message string `json:"message"`
+	}
+	switch {
+	case *email != "" && *token == "":
+		err := c.client.do(http.MethodPost, "/v1/users/sendResetPassword", map[string]string{"email": *email}, &message)
+		if err != nil {
+			return err
+		}
+	case *token != "" && *email == "":
+		password, err := promptPassword("New password: ")
+		if err != nil {
+			return err
+		}
+		confirm, err := promptPassword("Confirm password: ")
+		if err != nil {
+			return err
+		}
+		if password != confirm {
+			return errors.New("passwords do not match")
+		}
+		err = c.client.do(http.MethodPut, "/v1/users/resetPassword", map[string]string{"token": *token, "password": password}, &message)
+		if err != nil {
+			return err
+		}
+	default:
+		return errors.New("pass either -email or -token")
+	}
+	fmt.Fprintln(stdout, message.Message)
+	return nil
+}
+
+func (c *cli) printIdea(raw json.RawMessage) error {
+	if c.format == "json" {
+		return printJSON(raw)
+	}
+	var i idea
+	if err := json.Unmarshal(raw, &i); err != nil {
+		return err
+	}
+	return printTable([][]string{
+		{"ID:", strconv.Itoa(i.Id)},
+		{"Title:", i.Title},
+		{"Description:", i.Description},
+		{"Tags:", tagList(i.Tags)},
+		{"Created:", i.CreatedAt.Local().Format(time.DateTime)},
+	})
+}
+
+func ideaID(args []string) (string, error) {
+	if len(args) == 0 {
+		return "", errUsage
+	}
+	if _, err := strconv.Atoi(args[0]); err != nil {
+		return "", fmt.Errorf("invalid idea id %q", args[0])
+	}
+	return args[0], nil
+}
+
+func tagList(tags []tag) string {
+	titles := make([]string, len(tags))
+	for i, t := range tags {
+		titles[i] = t.Title
+	}
+	return strings.Join(titles, ", ")
+}
+
+// loginHint adds a reminder to log in again to 401 errors.
+func loginHint(err error) error {
+	if isUnauthorized(err) {
+		return fmt.Errorf("%w\nrun: ideas login", err)
+	}
+	return err
+}
+
+var stdin = bufio.NewReader(os.Stdin)
+
+func prompt(label string) string {
+	fmt.Fprint(os.Stderr, label)
+	line, _ := stdin.ReadString('\n')
+	return strings.TrimSpace(line)
+}
+
+// promptPassword reads a password without echoing it when stdin is a
+// terminal, and a plain line otherwise so that it can be piped in. Only the
+// line ending is removed, since spaces may be part of the password.
+func promptPassword(label string) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		fmt.Fprint(os.Stderr, label)
+		line, err := stdin.ReadString('\n')
+		if err != nil && line == "" {
+			return "", err
+		}
+		return strings.TrimRight(line, "\r\n"), nil
+	}
+	fmt.Fprint(os.Stderr, label)
+	b, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	return string(b), err
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// request is what the test server received.
+type request struct {
+	method string
+	path   string
+	auth   string
+	body   map[string]any
+}
+
+// newTestCLI starts a server answering with handler and returns a cli for
+// it. Requests are recorded, stdin reads from input, stdout is captured and
+// credentials are cached in a temporary config directory.
+func newTestCLI(t *testing.T, input string, handler http.HandlerFunc) (*cli, *[]request, *bytes.Buffer) {
+	t.Helper()
+	var requests []request
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := request{method: r.Method, path: r.URL.Path, auth: r.Header.Get("Authorization")}
+		b, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+		}
+		if len(b) > 0 {
+			if err := json.Unmarshal(b, &req.body); err != nil {
+				t.Errorf("request body %q: %v", b, err)
+			}
+		}
+		requests = append(requests, req)
+		handler(w, r)
+	}))
+	t.Cleanup(ts.Close)
+
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv("XDG_CONFIG_HOME", dir)
+
+	oldStdin, oldStdout := stdin, stdout
+	var out bytes.Buffer
+	stdin, stdout = bufio.NewReader(strings.NewReader(input)), &out
+	t.Cleanup(func() { stdin, stdout = oldStdin, oldStdout })
+
+	return &cli{client: newClient(ts.URL), format: "table"}, &requests, &out
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+var testIdea = map[string]any{"id": 1, "title": "An idea", "description": "Something", "tags": []any{}, "created_at": time.Now()}
+
+func TestLogin(t *testing.T) {
+	expires := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	c, requests, _ := newTestCLI(t, "alice@example.com\n  pass word \r\n", func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/tokens/authentication":
+			writeJSON(w, http.StatusCreated, map[string]any{"token": "secret", "expires_at": expires})
+		default:
+			writeJSON(w, http.StatusCreated, testIdea)
+		}
+	})
+
+	if err := c.run([]string{"login"}); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{"email": "alice@example.com", "password": "  pass word "}
+	if got := (*requests)[0].body; !equalJSON(got, want) {
+		t.Errorf("login sent %v, want %v", got, want)
+	}
+	credentials, err := loadCredentials()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cred := credentials[c.client.baseURL]; cred.Token != "secret" || !cred.ExpiresAt.Equal(expires) {
+		t.Errorf("cached %+v", cred)
+	}
+
+	// A new client, as on the next run, picks up the cached token.
+	c = &cli{client: newClient(c.client.baseURL), format: "table"}
+	if err := c.run([]string{"ideas", "create", "-title", "An idea", "-description", "Something", "-tags", "go, ,cli"}); err != nil {
+		t.Fatal(err)
+	}
+	req := (*requests)[1]
+	if req.auth != "Bearer secret" {
+		t.Errorf("got Authorization %q", req.auth)
+	}
+	if got, want := req.body["tags"], []any{map[string]any{"title": "go"}, map[string]any{"title": "cli"}}; !equalJSON(got, want) {
+		t.Errorf("got tags %v, want %v", got, want)
+	}
+
+	if err := c.run([]string{"logout"}); err != nil {
+		t.Fatal(err)
+	}
+	c = &cli{client: newClient(c.client.baseURL), format: "table"}
+	err = c.run([]string{"ideas", "delete", "1"})
+	if err == nil || !strings.Contains(err.Error(), "not logged in") {
+		t.Errorf("after logout: got error %v", err)
+	}
+	if len(*requests) != 2 {
+		t.Errorf("sent %d requests, want 2", len(*requests))
+	}
+}
+
+func TestLoginExpired(t *testing.T) {
+	c, _, _ := newTestCLI(t, "", nil)
+	err := saveCredentials(map[string]credential{
+		c.client.baseURL: {Token: "old", ExpiresAt: time.Now().Add(-time.Minute)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = c.authenticate()
+	if err == nil || !strings.Contains(err.Error(), "not logged in") {
+		t.Errorf("got error %v", err)
+	}
+}
+
+func TestEditIdea(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want map[string]any
+	}{
+		{"title", []string{"-title", "New"}, map[string]any{"title": "New"}},
+		{"description", []string{"-description", ""}, map[string]any{"description": ""}},
+		{"both", []string{"-title", "New", "-description", "Changed"}, map[string]any{"title": "New", "description": "Changed"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, requests, out := newTestCLI(t, "", func(w http.ResponseWriter, r *http.Request) {
+				writeJSON(w, http.StatusOK, testIdea)
+			})
+			err := saveCredentials(map[string]credential{
+				c.client.baseURL: {Token: "secret", ExpiresAt: time.Now().Add(time.Hour)},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = c.run(append([]string{"ideas", "edit", "1"}, tt.args...))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req := (*requests)[0]
+			if req.method != http.MethodPut || req.path != "/v1/ideas/1" {
+				t.Errorf("sent %s %s", req.method, req.path)
+			}
+			if !equalJSON(req.body, tt.want) {
+				t.Errorf("sent %v, want %v", req.body, tt.want)
+			}
+			if !strings.Contains(out.String(), "An idea") {
+				t.Errorf("got output %q", out)
+			}
+		})
+	}
+
+	c, requests, _ := newTestCLI(t, "", nil)
+	err := c.run([]string{"ideas", "edit", "1"})
+	if err == nil || !strings.Contains(err.Error(), "nothing to change") {
+		t.Errorf("no flags: got error %v", err)
+	}
+	err = c.run([]string{"ideas", "edit", "one", "-title", "New"})
+	if err == nil || !strings.Contains(err.Error(), "invalid idea id") {
+		t.Errorf("bad id: got error %v", err)
+	}
+	if len(*requests) != 0 {
+		t.Errorf("sent %d requests, want none", len(*requests))
+	}
+}
+
+func TestErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		want    string
+		wantErr func(error) bool
+	}{
+		{
+			name:   "validation",
+			status: http.StatusUnprocessableEntity,
+			body:   `{"status":422,"code":"failed_validation","detail":"the request contains invalid fields","errors":[{"field":"title","message":"must be provided"},{"field":"tags","message":"must contain at least 1 tag"}]}`,
+			want:   "the request contains invalid fields\n  title: must be provided\n  tags: must contain at least 1 tag",
+		},
+		{
+			name:    "unauthorized",
+			status:  http.StatusUnauthorized,
+			body:    `{"status":401,"code":"invalid_token","detail":"invalid or missing authentication token"}`,
+			want:    "invalid or missing authentication token\nrun: ideas login",
+			wantErr: isUnauthorized,
+		},
+		{
+			name:   "not problem json",
+			status: http.StatusBadGateway,
+			body:   "<html>bad gateway</html>",
+			want:   "POST /v1/ideas: 502 Bad Gateway",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _, _ := newTestCLI(t, "", func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/problem+json")
+				w.WriteHeader(tt.status)
+				io.WriteString(w, tt.body)
+			})
+			err := saveCredentials(map[string]credential{
+				c.client.baseURL: {Token: "secret", ExpiresAt: time.Now().Add(time.Hour)},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = c.run([]string{"ideas", "create", "-title", "An idea"})
+			if err == nil || err.Error() != tt.want {
+				t.Errorf("got error %q, want %q", err, tt.want)
+			}
+			if tt.wantErr != nil && !tt.wantErr(err) {
+				t.Errorf("error %v does not wrap the api error", err)
+			}
+		})
+	}
+}
+
+// equalJSON compares values decoded from JSON by their encoding.
+func equalJSON(got, want any) bool {
+	a, _ := json.Marshal(got)
+	b, _ := json.Marshal(want)
+	return bytes.Equal(a, b)
+}
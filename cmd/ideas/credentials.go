@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// credential is a token cached for one api.
+type credential struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// credentialsPath is the file caching tokens by api URL in the user's
+// config directory.
+func credentialsPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "projectideas", "credentials.json"), nil
+}
+
+func loadCredentials() (map[string]credential, error) {
+	path, err := credentialsPath()
+	if err != nil {
+		return nil, err
+	}
+	credentials := make(map[string]credential)
+	b, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return credentials, nil
+	case err != nil:
+		return nil, err
+	}
+	err = json.Unmarshal(b, &credentials)
+	return credentials, err
+}
+
+func saveCredentials(credentials map[string]credential) error {
+	path, err := credentialsPath()
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(path), 0o700)
+	if err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(credentials, "", "\t")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, b, 0o600)
+}
+
+// authenticate loads the cached token for the client's api.
+func (c *cli) authenticate() error {
+	credentials, err := loadCredentials()
+	if err != nil {
+		return err
+	}
+	cred, ok := credentials[c.client.baseURL]
+	if !ok || time.Now().After(cred.ExpiresAt) {
+		return errors.New("not logged in, run: ideas login")
+	}
+	c.client.token = cred.Token
+	return nil
+}
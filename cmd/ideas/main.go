@@ -0,0 +1,103 @@
+// Command ideas is a command-line client for the Project Ideas API.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+)
+
+const usage = `usage: ideas [flags] <command> [arguments]
+
+commands:
+  login                              log in and cache the token
+  logout                             forget the cached token
+  ideas list                         list ideas
+  ideas show <id>                    show an idea
+  ideas create -title T -description D -tags a,b
+                                     create an idea
+  ideas edit <id> [-title T] [-description D]
+                                     change one of your ideas
+  ideas delete <id>                  delete one of your ideas
+  tags list                          list tags used by ideas
+  account reset-password -email E    email a password reset token
+  account reset-password -token T    set a new password with a reset token
+
+flags:
+`
+
+type cli struct {
+	client *client
+	format string
+}
+
+func main() {
+	flags := flag.NewFlagSet("ideas", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), usage)
+		flags.PrintDefaults()
+	}
+	apiURL := flags.String("api", envOr("IDEAS_API", "http://localhost:4000"), "base URL of the api")
+	format := flags.String("format", "table", "output format (table|json)")
+	flags.Parse(os.Args[1:])
+
+	if *format != "table" && *format != "json" {
+		fmt.Fprintf(os.Stderr, "unknown format %q\n", *format)
+		os.Exit(2)
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	c := &cli{client: newClient(*apiURL), format: *format}
+	err := c.run(flags.Args())
+	switch {
+	case errors.Is(err, errUsage):
+		flags.Usage()
+		os.Exit(2)
+	case err != nil:
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
+
+var errUsage = errors.New("invalid usage")
+
+func (c *cli) run(args []string) error {
+	command := args[0]
+	var sub string
+	if len(args) > 1 {
+		sub = args[1]
+	}
+	switch {
+	case command == "login":
+		return c.login(args[1:])
+	case command == "logout":
+		return c.logout()
+	case command == "ideas" && sub == "list":
+		return c.listIdeas()
+	case command == "ideas" && sub == "show":
+		return c.showIdea(args[2:])
+	case command == "ideas" && sub == "create":
+		return c.createIdea(args[2:])
+	case command == "ideas" && sub == "edit":
+		return c.editIdea(args[2:])
+	case command == "ideas" && sub == "delete":
+		return c.deleteIdea(args[2:])
+	case command == "tags" && sub == "list":
+		return c.listTags()
+	case command == "account" && sub == "reset-password":
+		return c.resetPassword(args[2:])
+	default:
+		return errUsage
+	}
+}
+
+func envOr(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
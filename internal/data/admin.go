@@ -0,0 +1,326 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// IntegrityReport lists rows that the schema allows but the application
+// never creates on purpose.
+type IntegrityReport struct {
+	// OrphanIdeaTags are ideas_tags rows that point at no idea or no tag.
+	OrphanIdeaTags int64 `json:"orphan_idea_tags"`
+	// DuplicateIdeaTags are repeated links between the same idea and tag.
+	DuplicateIdeaTags int64 `json:"duplicate_idea_tags"`
+	// DuplicateTags are extra tags sharing the title of an older one.
+	DuplicateTags int64 `json:"duplicate_tags"`
+	// UnusedTags are tags that no idea uses and nobody follows.
+	UnusedTags int64 `json:"unused_tags"`
+	// UntaggedIdeas are ideas without tags, which the listings leave out.
+	UntaggedIdeas []int `json:"untagged_ideas"`
+}
+
+type TagCount struct {
+	Title string `json:"title"`
+	Ideas int    `json:"ideas"`
+}
+
+type Stats struct {
+	Users           int        `json:"users"`
+	Admins          int        `json:"admins"`
+	BannedUsers     int        `json:"banned_users"`
+	PendingDeletion int        `json:"pending_deletion"`
+	Ideas           int        `json:"ideas"`
+	Tags            int        `json:"tags"`
+	ActiveTokens    int        `json:"active_tokens"`
+	ExpiredTokens   int        `json:"expired_tokens"`
+	TopTags         []TagCount `json:"top_tags"`
+}
+
+// AdminModel holds queries that span several tables and are only run by
+// operators, never by request handlers.
+type AdminModel struct {
+	DB           *sql.DB
+	QueryTimeout time.Duration
+}
+
+type queryer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// MergeTags moves the ideas and followers of the tag titled from to the tag
+// titled into, which is created by renaming from if it does not exist, and
+// deletes from. Merging a title into itself collapses duplicate tags. It
+// returns the number of ideas that were given the tag into.
+func (m AdminModel) MergeTags(ctx context.Context, from, into string) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.QueryTimeout)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	n, err := mergeTags(ctx, tx, from, into)
+	if err != nil {
+		return 0, err
+	}
+	return n, tx.Commit()
+}
+
+func mergeTags(ctx context.Context, q queryer, from, into string) (int64, error) {
+	existsQuery := `
+	SELECT EXISTS (SELECT 1 FROM tags WHERE title = $1)`
+	selectQuery := `
+	SELECT id FROM tags
+	WHERE title = $1
+	ORDER BY id
+	LIMIT 1`
+	renameQuery := `
+	UPDATE tags
+	SET title = $1
+	WHERE id = (SELECT min(id) FROM tags WHERE title = $2)
+	RETURNING id`
+	ideasQuery := `
+	INSERT INTO ideas_tags (idea_id, tag_id)
+	SELECT DISTINCT idea_id, $1::int FROM ideas_tags
+	WHERE tag_id IN (SELECT id FROM tags WHERE title = $2 AND id <> $1)
+	AND idea_id NOT IN (SELECT idea_id FROM ideas_tags WHERE tag_id = $1 AND idea_id IS NOT NULL)`
+	followsQuery := `
+	INSERT INTO follows (follower_id, tag_id, created_at)
+	SELECT follower_id, $1::int, min(created_at) FROM follows
+	WHERE tag_id IN (SELECT id FROM tags WHERE title = $2 AND id <> $1)
+	GROUP BY follower_id
+	ON CONFLICT DO NOTHING`
+	deleteQuery := `
+	DELETE FROM tags
+	WHERE title = $2 AND id <> $1`
+
+	var exists bool
+	err := q.QueryRowContext(ctx, existsQuery, from).Scan(&exists)
+	if err != nil {
+		return 0, err
+	}
+	if !exists {
+		return 0, ErrNoRows
+	}
+	var intoId int
+	err = q.QueryRowContext(ctx, selectQuery, into).Scan(&intoId)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = q.QueryRowContext(ctx, renameQuery, into, from).Scan(&intoId)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := q.ExecContext(ctx, ideasQuery, intoId, from)
+	if err != nil {
+		return 0, err
+	}
+	merged, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	_, err = q.ExecContext(ctx, followsQuery, intoId, from)
+	if err != nil {
+		return 0, err
+	}
+	// The old links to from go with it through ON DELETE CASCADE.
+	_, err = q.ExecContext(ctx, deleteQuery, intoId, from)
+	if err != nil {
+		return 0, err
+	}
+	return merged, nil
+}
+
+func (m AdminModel) CheckIntegrity(ctx context.Context) (*IntegrityReport, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.QueryTimeout)
+	defer cancel()
+
+	return checkIntegrity(ctx, m.DB)
+}
+
+func checkIntegrity(ctx context.Context, q queryer) (*IntegrityReport, error) {
+	countsQuery := `
+	SELECT
+		(SELECT count(*) FROM ideas_tags it
+		WHERE NOT EXISTS (SELECT 1 FROM ideas WHERE ideas.id = it.idea_id)
+		OR NOT EXISTS (SELECT 1 FROM tags WHERE tags.id = it.tag_id)),
+		(SELECT coalesce(sum(n - 1), 0) FROM (
+			SELECT count(*) AS n FROM ideas_tags
+			GROUP BY idea_id, tag_id
+			HAVING count(*) > 1
+		) d),
+		(SELECT coalesce(sum(n - 1), 0) FROM (
+			SELECT count(*) AS n FROM tags
+			GROUP BY title
+			HAVING count(*) > 1
+		) d),
+		(SELECT count(*) FROM tags
+		WHERE NOT EXISTS (SELECT 1 FROM ideas_tags WHERE tag_id = tags.id)
+		AND NOT EXISTS (SELECT 1 FROM follows WHERE tag_id = tags.id))`
+	untaggedQuery := `
+	SELECT id FROM ideas
+	WHERE NOT EXISTS (SELECT 1 FROM ideas_tags WHERE idea_id = ideas.id)
+	ORDER BY id`
+
+	var report IntegrityReport
+	err := q.QueryRowContext(ctx, countsQuery).Scan(
+		&report.OrphanIdeaTags,
+		&report.DuplicateIdeaTags,
+		&report.DuplicateTags,
+		&report.UnusedTags,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := q.QueryContext(ctx, untaggedQuery)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	report.UntaggedIdeas = []int{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		report.UntaggedIdeas = append(report.UntaggedIdeas, id)
+	}
+	return &report, rows.Err()
+}
+
+// RepairIntegrity removes orphan and duplicate ideas_tags rows, merges tags
+// sharing a title and deletes unused tags. It returns what it found before
+// repairing; untagged ideas are only reported since their tags are lost.
+func (m AdminModel) RepairIntegrity(ctx context.Context) (*IntegrityReport, error) {
+	orphansQuery := `
+	DELETE FROM ideas_tags it
+	WHERE NOT EXISTS (SELECT 1 FROM ideas WHERE ideas.id = it.idea_id)
+	OR NOT EXISTS (SELECT 1 FROM tags WHERE tags.id = it.tag_id)`
+	duplicatesQuery := `
+	DELETE FROM ideas_tags a
+	USING ideas_tags b
+	WHERE a.idea_id = b.idea_id
+	AND a.tag_id = b.tag_id
+	AND a.ctid > b.ctid`
+	duplicateTagsQuery := `
+	SELECT title FROM tags
+	GROUP BY title
+	HAVING count(*) > 1`
+	unusedQuery := `
+	DELETE FROM tags
+	WHERE NOT EXISTS (SELECT 1 FROM ideas_tags WHERE tag_id = tags.id)
+	AND NOT EXISTS (SELECT 1 FROM follows WHERE tag_id = tags.id)`
+	ctx, cancel := context.WithTimeout(ctx, m.QueryTimeout)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	report, err := checkIntegrity(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+	for _, query := range []string{orphansQuery, duplicatesQuery} {
+		_, err = tx.ExecContext(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	rows, err := tx.QueryContext(ctx, duplicateTagsQuery)
+	if err != nil {
+		return nil, err
+	}
+	var titles []string
+	for rows.Next() {
+		var title string
+		if err := rows.Scan(&title); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		titles = append(titles, title)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	for _, title := range titles {
+		_, err = mergeTags(ctx, tx, title, title)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, unusedQuery)
+	if err != nil {
+		return nil, err
+	}
+	return report, tx.Commit()
+}
+
+// Stats counts users, ideas, tags and tokens. The ghost account is not
+// counted as a user.
+func (m AdminModel) Stats(ctx context.Context) (*Stats, error) {
+	countsQuery := `
+	SELECT
+		(SELECT count(*) FROM users WHERE NOT is_ghost),
+		(SELECT count(*) FROM users WHERE is_admin),
+		(SELECT count(*) FROM users WHERE banned_at IS NOT NULL),
+		(SELECT count(*) FROM users WHERE delete_after IS NOT NULL),
+		(SELECT count(*) FROM ideas),
+		(SELECT count(*) FROM tags),
+		(SELECT count(*) FROM tokens WHERE expires_at > now()),
+		(SELECT count(*) FROM tokens WHERE expires_at <= now())`
+	topTagsQuery := `
+	SELECT tags.title, count(DISTINCT ideas_tags.idea_id)
+	FROM tags
+	JOIN ideas_tags ON ideas_tags.tag_id = tags.id
+	GROUP BY tags.title
+	ORDER BY 2 DESC, 1
+	LIMIT 10`
+	ctx, cancel := context.WithTimeout(ctx, m.QueryTimeout)
+	defer cancel()
+
+	var stats Stats
+	err := m.DB.QueryRowContext(ctx, countsQuery).Scan(
+		&stats.Users,
+		&stats.Admins,
+		&stats.BannedUsers,
+		&stats.PendingDeletion,
+		&stats.Ideas,
+		&stats.Tags,
+		&stats.ActiveTokens,
+		&stats.ExpiredTokens,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := m.DB.QueryContext(ctx, topTagsQuery)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stats.TopTags = []TagCount{}
+	for rows.Next() {
+		var tag TagCount
+		if err := rows.Scan(&tag.Title, &tag.Ideas); err != nil {
+			return nil, err
+		}
+		stats.TopTags = append(stats.TopTags, tag)
+	}
+	return &stats, rows.Err()
+}
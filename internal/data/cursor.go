@@ -0,0 +1,54 @@
+package data
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor points at the last row of a page ordered by creation time and id,
+// both descending. The next page starts right after it.
+type Cursor struct {
+	CreatedAt time.Time
+	Id        int
+}
+
+func (c Cursor) Encode() string {
+	raw := c.CreatedAt.UTC().Format(time.RFC3339Nano) + "," + strconv.Itoa(c.Id)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func DecodeCursor(s string) (*Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	createdAt, id, found := strings.Cut(string(raw), ",")
+	if !found {
+		return nil, ErrInvalidCursor
+	}
+	var cursor Cursor
+	cursor.CreatedAt, err = time.Parse(time.RFC3339Nano, createdAt)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	cursor.Id, err = strconv.Atoi(id)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return &cursor, nil
+}
+
+// cursorArgs turns an optional cursor into query arguments. A nil cursor
+// yields NULLs so queries can select the first page with
+// `$n::timestamptz IS NULL OR (created_at, id) < ($n, $m)`.
+func cursorArgs(c *Cursor) (any, any) {
+	if c == nil {
+		return nil, nil
+	}
+	return c.CreatedAt, c.Id
+}
@@ -0,0 +1,154 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+const (
+	DigestNever  = "never"
+	DigestDaily  = "daily"
+	DigestWeekly = "weekly"
+)
+
+var DigestFrequencies = []string{DigestNever, DigestDaily, DigestWeekly}
+
+// Digest holds what happened since Since for one subscriber.
+type Digest struct {
+	User          User
+	Frequency     string
+	Since         time.Time
+	TagIdeas      []Idea
+	FollowedIdeas []Idea
+}
+
+func (d *Digest) Empty() bool {
+	return len(d.TagIdeas) == 0 && len(d.FollowedIdeas) == 0
+}
+
+type DigestModel struct {
+	DB           *sql.DB
+	QueryTimeout time.Duration
+}
+
+// Due returns an empty digest for every user whose next digest is due, with
+// Since set to when the previous one was sent. Banned users and users
+// pending deletion get no digest.
+func (m DigestModel) Due(ctx context.Context) ([]Digest, error) {
+	query := `
+	SELECT id, username, email, created_at, digest_frequency,
+		COALESCE(digest_sent_at, now() - CASE digest_frequency WHEN 'daily' THEN interval '1 day' ELSE interval '7 days' END)
+	FROM users
+	WHERE delete_after IS NULL
+	AND banned_at IS NULL
+	AND (
+		(digest_frequency = 'daily' AND (digest_sent_at IS NULL OR digest_sent_at <= now() - interval '1 day'))
+		OR (digest_frequency = 'weekly' AND (digest_sent_at IS NULL OR digest_sent_at <= now() - interval '7 days'))
+	)`
+	ctx, cancel := context.WithTimeout(ctx, m.QueryTimeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var digests []Digest
+	for rows.Next() {
+		var d Digest
+		err := rows.Scan(&d.User.Id, &d.User.Username, &d.User.Email, &d.User.CreatedAt, &d.Frequency, &d.Since)
+		if err != nil {
+			return nil, err
+		}
+		digests = append(digests, d)
+	}
+	return digests, rows.Err()
+}
+
+// Fill loads the ideas created since d.Since in tags and by users that the
+// digest's user follows, limited to limit ideas each.
+func (m DigestModel) Fill(ctx context.Context, d *Digest, limit int) error {
+	tagQuery := `
+	SELECT DISTINCT ideas.id, ideas.title, ideas.description, ideas.user_id, ideas.created_at
+	FROM ideas
+	JOIN ideas_tags ON ideas_tags.idea_id = ideas.id
+	JOIN follows ON follows.tag_id = ideas_tags.tag_id
+	WHERE follows.follower_id = $1
+	AND ideas.user_id <> $1
+	AND ideas.created_at > $2
+	ORDER BY ideas.created_at DESC
+	LIMIT $3`
+	userQuery := `
+	SELECT ideas.id, ideas.title, ideas.description, ideas.user_id, ideas.created_at
+	FROM ideas
+	JOIN follows ON follows.user_id = ideas.user_id
+	WHERE follows.follower_id = $1
+	AND ideas.created_at > $2
+	ORDER BY ideas.created_at DESC
+	LIMIT $3`
+	ctx, cancel := context.WithTimeout(ctx, m.QueryTimeout)
+	defer cancel()
+
+	var err error
+	d.TagIdeas, err = m.listIdeas(ctx, tagQuery, d.User.Id, d.Since, limit)
+	if err != nil {
+		return err
+	}
+	d.FollowedIdeas, err = m.listIdeas(ctx, userQuery, d.User.Id, d.Since, limit)
+	return err
+}
+
+func (m DigestModel) listIdeas(ctx context.Context, query string, args ...any) ([]Idea, error) {
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ideas []Idea
+	for rows.Next() {
+		var idea Idea
+		err := rows.Scan(&idea.Id, &idea.Title, &idea.Description, &idea.UserId, &idea.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		ideas = append(ideas, idea)
+	}
+	return ideas, rows.Err()
+}
+
+func (m DigestModel) MarkSent(ctx context.Context, userId int, sentAt time.Time) error {
+	query := `
+	UPDATE users
+	SET digest_sent_at = $1
+	WHERE id = $2`
+	ctx, cancel := context.WithTimeout(ctx, m.QueryTimeout)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, sentAt, userId)
+	return err
+}
+
+func (m DigestModel) SetFrequency(ctx context.Context, userId int, frequency string) error {
+	query := `
+	UPDATE users
+	SET digest_frequency = $1
+	WHERE id = $2`
+	ctx, cancel := context.WithTimeout(ctx, m.QueryTimeout)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, frequency, userId)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,204 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+type Profile struct {
+	Id        int       `json:"id"`
+	Username  string    `json:"username"`
+	Followers int       `json:"followers"`
+	Following int       `json:"following"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type FollowModel struct {
+	DB           *sql.DB
+	QueryTimeout time.Duration
+}
+
+const profileColumns = `users.id, users.username, users.created_at,
+	(SELECT count(*) FROM follows f WHERE f.user_id = users.id),
+	(SELECT count(*) FROM follows f WHERE f.follower_id = users.id AND f.user_id IS NOT NULL)`
+
+func (m FollowModel) Profile(ctx context.Context, userId int) (*Profile, error) {
+	query := `
+	SELECT ` + profileColumns + `
+	FROM users
+	WHERE users.id = $1`
+	ctx, cancel := context.WithTimeout(ctx, m.QueryTimeout)
+	defer cancel()
+
+	var profile Profile
+	err := m.DB.QueryRowContext(ctx, query, userId).Scan(&profile.Id, &profile.Username, &profile.CreatedAt, &profile.Followers, &profile.Following)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrNoRows
+		default:
+			return nil, err
+		}
+	}
+	return &profile, nil
+}
+
+func (m FollowModel) Followers(ctx context.Context, userId int) ([]Profile, error) {
+	query := `
+	SELECT ` + profileColumns + `
+	FROM follows
+	JOIN users ON users.id = follows.follower_id
+	WHERE follows.user_id = $1
+	ORDER BY follows.created_at DESC`
+	return m.listProfiles(ctx, query, userId)
+}
+
+func (m FollowModel) Following(ctx context.Context, userId int) ([]Profile, error) {
+	query := `
+	SELECT ` + profileColumns + `
+	FROM follows
+	JOIN users ON users.id = follows.user_id
+	WHERE follows.follower_id = $1
+	ORDER BY follows.created_at DESC`
+	return m.listProfiles(ctx, query, userId)
+}
+
+func (m FollowModel) listProfiles(ctx context.Context, query string, args ...any) ([]Profile, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.QueryTimeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	profiles := []Profile{}
+	for rows.Next() {
+		var profile Profile
+		err := rows.Scan(&profile.Id, &profile.Username, &profile.CreatedAt, &profile.Followers, &profile.Following)
+		if err != nil {
+			return nil, err
+		}
+		profiles = append(profiles, profile)
+	}
+	return profiles, rows.Err()
+}
+
+func (m FollowModel) FollowedTags(ctx context.Context, userId int) ([]Tag, error) {
+	query := `
+	SELECT tags.id, tags.title
+	FROM follows
+	JOIN tags ON tags.id = follows.tag_id
+	WHERE follows.follower_id = $1
+	ORDER BY tags.title`
+	ctx, cancel := context.WithTimeout(ctx, m.QueryTimeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []Tag{}
+	for rows.Next() {
+		var tag Tag
+		err := rows.Scan(&tag.Id, &tag.Title)
+		if err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
+
+// FollowUser makes followerId follow userId. Following someone twice is not
+// an error; the returned bool reports whether a new follow was created.
+func (m FollowModel) FollowUser(ctx context.Context, followerId, userId int) (bool, error) {
+	query := `
+	INSERT INTO follows (follower_id, user_id)
+	VALUES ($1, $2)
+	ON CONFLICT DO NOTHING`
+	ctx, cancel := context.WithTimeout(ctx, m.QueryTimeout)
+	defer cancel()
+
+	exists, err := m.exists(ctx, `SELECT EXISTS (SELECT 1 FROM users WHERE id = $1)`, userId)
+	if err != nil {
+		return false, err
+	}
+	if !exists {
+		return false, ErrNoRows
+	}
+	result, err := m.DB.ExecContext(ctx, query, followerId, userId)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected > 0, nil
+}
+
+func (m FollowModel) UnfollowUser(ctx context.Context, followerId, userId int) error {
+	query := `
+	DELETE FROM follows
+	WHERE follower_id = $1 AND user_id = $2`
+	return m.delete(ctx, query, followerId, userId)
+}
+
+// FollowTag makes followerId follow the tag with the given title. Following a
+// tag twice is not an error.
+func (m FollowModel) FollowTag(ctx context.Context, followerId int, title string) error {
+	query := `
+	INSERT INTO follows (follower_id, tag_id)
+	SELECT $1, id FROM tags WHERE title = $2
+	ON CONFLICT DO NOTHING`
+	ctx, cancel := context.WithTimeout(ctx, m.QueryTimeout)
+	defer cancel()
+
+	exists, err := m.exists(ctx, `SELECT EXISTS (SELECT 1 FROM tags WHERE title = $1)`, title)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrNoRows
+	}
+	_, err = m.DB.ExecContext(ctx, query, followerId, title)
+	return err
+}
+
+func (m FollowModel) UnfollowTag(ctx context.Context, followerId int, title string) error {
+	query := `
+	DELETE FROM follows
+	WHERE follower_id = $1
+	AND tag_id IN (SELECT id FROM tags WHERE title = $2)`
+	return m.delete(ctx, query, followerId, title)
+}
+
+func (m FollowModel) exists(ctx context.Context, query string, args ...any) (bool, error) {
+	var exists bool
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&exists)
+	return exists, err
+}
+
+func (m FollowModel) delete(ctx context.Context, query string, args ...any) error {
+	ctx, cancel := context.WithTimeout(ctx, m.QueryTimeout)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrNoRows
+	}
+	return nil
+}
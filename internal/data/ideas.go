@@ -6,6 +6,7 @@ import (
 	"errors"
 	"time"
 
+	"github.com/lib/pq"
 	"github.com/sulavmhrzn/projectideas/internal/validator"
 )
 
@@ -24,32 +25,31 @@ type Tag struct {
 }
 
 func ValidateIdea(v *validator.Validator, idea *Idea) {
-	v.Check(idea.Title != "", "title", "must be provided")
-	v.Check(len(idea.Title) < 100, "title", "must be smaller than 100 characters")
-	v.Check(idea.Description != "", "description", "must be provided")
-	v.Check(len(idea.Tags) != 0, "tags", "must be provided")
-	for _, i := range idea.Tags {
-		v.Check(i.Title != "", "title", "tags title must be provided")
-	}
+	v.Check(idea.Title != "", "title", validator.Required)
+	v.Check(validator.MaxRunes(idea.Title, 99), "title", validator.TooLong, 99)
+	v.Check(idea.Description != "", "description", validator.Required)
+	v.Check(len(idea.Tags) != 0, "tags", validator.Required)
 	var tagTitles []string
-	for _, i := range idea.Tags {
-		tagTitles = append(tagTitles, i.Title)
+	for i, tag := range idea.Tags {
+		v.Index("tags", i).Check(tag.Title != "", "title", validator.Required)
+		tagTitles = append(tagTitles, tag.Title)
 	}
-	v.Check(validator.Unique(tagTitles...), "title", "tag title must be unique")
+	v.Check(validator.Unique(tagTitles...), "tags", validator.NotUnique)
 }
 
 type IdeaModel struct {
-	DB *sql.DB
+	DB           *sql.DB
+	QueryTimeout time.Duration
 }
 
-func (m IdeaModel) Insert(input *Idea) (*Idea, error) {
+func (m IdeaModel) Insert(ctx context.Context, input *Idea) (*Idea, error) {
 	insertIdeaQuery := `
 	INSERT INTO ideas 
 	(title, description, user_id)
 	VALUES 
 	($1, $2, $3)
 	RETURNING id, title, description, created_at`
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, m.QueryTimeout)
 	defer cancel()
 
 	var idea Idea
@@ -92,14 +92,14 @@ func (m IdeaModel) Insert(input *Idea) (*Idea, error) {
 	return &idea, nil
 }
 
-func (m IdeaModel) List() ([]Idea, error) {
+func (m IdeaModel) List(ctx context.Context) ([]Idea, error) {
 	query := `
 	SELECT ideas.id, ideas.title, ideas.description, ideas.created_at, tags.id, tags.title
 	FROM ideas
 	JOIN ideas_tags ON ideas_tags.idea_id = ideas.id
 	JOIN tags ON ideas_tags.tag_id = tags.id
 	ORDER BY created_at DESC`
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, m.QueryTimeout)
 	defer cancel()
 
 	rows, err := m.DB.QueryContext(ctx, query)
@@ -140,14 +140,131 @@ func (m IdeaModel) List() ([]Idea, error) {
 	return ideas, nil
 }
 
-func (m IdeaModel) Get(id int) (*Idea, error) {
+// ListForUser returns every idea of userId, newest first, including ideas
+// without tags.
+func (m IdeaModel) ListForUser(ctx context.Context, userId int) ([]Idea, error) {
+	query := `
+	SELECT id, title, description, created_at
+	FROM ideas
+	WHERE user_id = $1
+	ORDER BY created_at DESC, id DESC`
+	ctx, cancel := context.WithTimeout(ctx, m.QueryTimeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ideas := []Idea{}
+	for rows.Next() {
+		idea := Idea{UserId: userId, Tags: []Tag{}}
+		err := rows.Scan(&idea.Id, &idea.Title, &idea.Description, &idea.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		ideas = append(ideas, idea)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return ideas, m.attachTags(ctx, ideas)
+}
+
+// Feed returns up to limit ideas written by users or tagged with tags that
+// userId follows, newest first, starting after cursor.
+func (m IdeaModel) Feed(ctx context.Context, userId int, cursor *Cursor, limit int) ([]Idea, error) {
+	query := `
+	SELECT ideas.id, ideas.title, ideas.description, ideas.user_id, ideas.created_at
+	FROM ideas
+	WHERE (
+		ideas.user_id IN (
+			SELECT user_id FROM follows
+			WHERE follower_id = $1 AND user_id IS NOT NULL
+		)
+		OR ideas.id IN (
+			SELECT ideas_tags.idea_id FROM ideas_tags
+			JOIN follows ON follows.tag_id = ideas_tags.tag_id
+			WHERE follows.follower_id = $1
+		)
+	)
+	AND ($2::timestamptz IS NULL OR (ideas.created_at, ideas.id) < ($2, $3))
+	ORDER BY ideas.created_at DESC, ideas.id DESC
+	LIMIT $4`
+	ctx, cancel := context.WithTimeout(ctx, m.QueryTimeout)
+	defer cancel()
+
+	createdAt, id := cursorArgs(cursor)
+	rows, err := m.DB.QueryContext(ctx, query, userId, createdAt, id, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ideas := []Idea{}
+	for rows.Next() {
+		var idea Idea
+		err := rows.Scan(&idea.Id, &idea.Title, &idea.Description, &idea.UserId, &idea.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		ideas = append(ideas, idea)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	err = m.attachTags(ctx, ideas)
+	if err != nil {
+		return nil, err
+	}
+	return ideas, nil
+}
+
+func (m IdeaModel) attachTags(ctx context.Context, ideas []Idea) error {
+	if len(ideas) == 0 {
+		return nil
+	}
+	query := `
+	SELECT ideas_tags.idea_id, tags.id, tags.title
+	FROM ideas_tags
+	JOIN tags ON tags.id = ideas_tags.tag_id
+	WHERE ideas_tags.idea_id = ANY($1)`
+
+	ids := make([]int64, 0, len(ideas))
+	index := make(map[int]int, len(ideas))
+	for i, idea := range ideas {
+		ids = append(ids, int64(idea.Id))
+		index[idea.Id] = i
+	}
+	rows, err := m.DB.QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var ideaId int
+		var tag Tag
+		err := rows.Scan(&ideaId, &tag.Id, &tag.Title)
+		if err != nil {
+			return err
+		}
+		i := index[ideaId]
+		ideas[i].Tags = append(ideas[i].Tags, tag)
+	}
+	return rows.Err()
+}
+
+func (m IdeaModel) Get(ctx context.Context, id int) (*Idea, error) {
 	query := `SELECT ideas.id, ideas.title, ideas.description, ideas.created_at, tags.id, tags.title
 	FROM ideas
 	JOIN ideas_tags ON ideas_tags.idea_id = ideas.id
 	JOIN tags ON ideas_tags.tag_id = tags.id
 	WHERE ideas.id = $1
 	ORDER BY created_at DESC`
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, m.QueryTimeout)
 	defer cancel()
 
 	rows, err := m.DB.QueryContext(ctx, query, id)
@@ -187,10 +304,10 @@ func (m IdeaModel) Get(id int) (*Idea, error) {
 	return idea, nil
 }
 
-func (m IdeaModel) Delete(ideaId, userId int) error {
+func (m IdeaModel) Delete(ctx context.Context, ideaId, userId int) error {
 	query := `
 	DELETE FROM ideas WHERE id = $1 AND user_id = $2`
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, m.QueryTimeout)
 	defer cancel()
 
 	result, err := m.DB.ExecContext(ctx, query, ideaId, userId)
@@ -209,7 +326,7 @@ func (m IdeaModel) Delete(ideaId, userId int) error {
 }
 
 // TODO: This method should update tags as well
-func (m IdeaModel) Update(ideaId, userId int, input *Idea) (*Idea, error) {
+func (m IdeaModel) Update(ctx context.Context, ideaId, userId int, input *Idea) (*Idea, error) {
 	query := `
 	UPDATE ideas SET
 	title = COALESCE(NULLIF($1, ''), title),
@@ -218,7 +335,7 @@ func (m IdeaModel) Update(ideaId, userId int, input *Idea) (*Idea, error) {
 	AND user_id = $4
 	RETURNING id, title, description, created_at
 	`
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, m.QueryTimeout)
 	defer cancel()
 
 	var idea Idea
@@ -0,0 +1,236 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/sulavmhrzn/projectideas/internal/data"
+)
+
+// Admin implements the operator queries. Ideas keep their tags in a slice
+// rather than a join table, so orphan links cannot occur here.
+type Admin struct {
+	s *store
+}
+
+func (m *Admin) MergeTags(ctx context.Context, from, into string) (int64, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	return m.s.mergeTags(from, into)
+}
+
+func (s *store) mergeTags(from, into string) (int64, error) {
+	source, ok := s.tagByTitle(from)
+	if !ok {
+		return 0, data.ErrNoRows
+	}
+	target, ok := s.tagByTitle(into)
+	if !ok {
+		target = source
+		for i := range s.tags {
+			if s.tags[i].Id == target.Id {
+				s.tags[i].Title = into
+			}
+		}
+	}
+	target.Title = into
+
+	// Ids of the tags to drop: every tag titled from except the target.
+	dropped := make(map[int]bool)
+	tags := s.tags[:0]
+	for _, t := range s.tags {
+		if t.Title == from && t.Id != target.Id {
+			dropped[t.Id] = true
+			continue
+		}
+		tags = append(tags, t)
+	}
+	s.tags = tags
+
+	var merged int64
+	for _, idea := range s.ideas {
+		var kept []data.Tag
+		hasTarget, hadDropped := false, false
+		for _, t := range idea.Tags {
+			switch {
+			case dropped[t.Id]:
+				hadDropped = true
+			case t.Id == target.Id:
+				hasTarget = true
+				kept = append(kept, target)
+			default:
+				kept = append(kept, t)
+			}
+		}
+		if hadDropped && !hasTarget {
+			kept = append(kept, target)
+			merged++
+		}
+		idea.Tags = kept
+	}
+
+	followed := make(map[int]bool)
+	for _, f := range s.follows {
+		if f.tagId == target.Id {
+			followed[f.followerId] = true
+		}
+	}
+	follows := s.follows[:0]
+	for _, f := range s.follows {
+		if dropped[f.tagId] {
+			if followed[f.followerId] {
+				continue
+			}
+			followed[f.followerId] = true
+			f.tagId = target.Id
+		}
+		follows = append(follows, f)
+	}
+	s.follows = follows
+	return merged, nil
+}
+
+func (m *Admin) CheckIntegrity(ctx context.Context) (*data.IntegrityReport, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	return m.s.checkIntegrity(), nil
+}
+
+func (s *store) checkIntegrity() *data.IntegrityReport {
+	report := &data.IntegrityReport{UntaggedIdeas: []int{}}
+	titles := make(map[string]bool)
+	for _, t := range s.tags {
+		if titles[t.Title] {
+			report.DuplicateTags++
+		}
+		titles[t.Title] = true
+		if !s.tagUsed(t.Id) {
+			report.UnusedTags++
+		}
+	}
+	for id, idea := range s.ideas {
+		if len(idea.Tags) == 0 {
+			report.UntaggedIdeas = append(report.UntaggedIdeas, id)
+		}
+		seen := make(map[int]bool)
+		for _, t := range idea.Tags {
+			if seen[t.Id] {
+				report.DuplicateIdeaTags++
+			}
+			seen[t.Id] = true
+		}
+	}
+	sort.Ints(report.UntaggedIdeas)
+	return report
+}
+
+func (s *store) tagUsed(id int) bool {
+	for _, idea := range s.ideas {
+		for _, t := range idea.Tags {
+			if t.Id == id {
+				return true
+			}
+		}
+	}
+	for _, f := range s.follows {
+		if f.tagId == id {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *Admin) RepairIntegrity(ctx context.Context) (*data.IntegrityReport, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	report := m.s.checkIntegrity()
+	for _, idea := range m.s.ideas {
+		seen := make(map[int]bool)
+		tags := idea.Tags[:0]
+		for _, t := range idea.Tags {
+			if !seen[t.Id] {
+				tags = append(tags, t)
+			}
+			seen[t.Id] = true
+		}
+		idea.Tags = tags
+	}
+	titles := make(map[string]int)
+	for _, t := range m.s.tags {
+		titles[t.Title]++
+	}
+	for title, n := range titles {
+		if n > 1 {
+			m.s.mergeTags(title, title)
+		}
+	}
+	tags := m.s.tags[:0]
+	for _, t := range m.s.tags {
+		if m.s.tagUsed(t.Id) {
+			tags = append(tags, t)
+		}
+	}
+	m.s.tags = tags
+	return report, nil
+}
+
+func (m *Admin) Stats(ctx context.Context) (*data.Stats, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	stats := &data.Stats{
+		Ideas:   len(m.s.ideas),
+		Tags:    len(m.s.tags),
+		TopTags: []data.TagCount{},
+	}
+	for _, u := range m.s.users {
+		if !u.ghost {
+			stats.Users++
+		}
+		if u.IsAdmin {
+			stats.Admins++
+		}
+		if u.Banned() {
+			stats.BannedUsers++
+		}
+		if u.deleteAfter != nil {
+			stats.PendingDeletion++
+		}
+	}
+	for _, t := range m.s.tokens {
+		if t.ExpiresAt.After(time.Now()) {
+			stats.ActiveTokens++
+		} else {
+			stats.ExpiredTokens++
+		}
+	}
+
+	counts := make(map[string]int)
+	for _, idea := range m.s.ideas {
+		seen := make(map[string]bool)
+		for _, t := range idea.Tags {
+			if !seen[t.Title] {
+				counts[t.Title]++
+			}
+			seen[t.Title] = true
+		}
+	}
+	for title, n := range counts {
+		stats.TopTags = append(stats.TopTags, data.TagCount{Title: title, Ideas: n})
+	}
+	sort.Slice(stats.TopTags, func(i, j int) bool {
+		a, b := stats.TopTags[i], stats.TopTags[j]
+		if a.Ideas != b.Ideas {
+			return a.Ideas > b.Ideas
+		}
+		return a.Title < b.Title
+	})
+	if len(stats.TopTags) > 10 {
+		stats.TopTags = stats.TopTags[:10]
+	}
+	return stats, nil
+}
@@ -0,0 +1,106 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/sulavmhrzn/projectideas/internal/data"
+)
+
+type Digests struct {
+	s *store
+}
+
+var digestPeriods = map[string]time.Duration{
+	data.DigestDaily:  24 * time.Hour,
+	data.DigestWeekly: 7 * 24 * time.Hour,
+}
+
+func (m *Digests) Due(ctx context.Context) ([]data.Digest, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	now := time.Now()
+	var digests []data.Digest
+	for _, u := range m.s.users {
+		period, ok := digestPeriods[u.digestFrequency]
+		if !ok || u.deleteAfter != nil || u.Banned() {
+			continue
+		}
+		since := now.Add(-period)
+		if u.digestSentAt != nil {
+			if u.digestSentAt.After(since) {
+				continue
+			}
+			since = *u.digestSentAt
+		}
+		recipient := u.User
+		recipient.Password = data.User{}.Password
+		digests = append(digests, data.Digest{User: recipient, Frequency: u.digestFrequency, Since: since})
+	}
+	return digests, nil
+}
+
+func (m *Digests) Fill(ctx context.Context, d *data.Digest, limit int) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	var tagIdeas, followedIdeas []data.Idea
+	for _, idea := range m.s.ideas {
+		if !idea.CreatedAt.After(d.Since) {
+			continue
+		}
+		for _, f := range m.s.follows {
+			if f.followerId != d.User.Id {
+				continue
+			}
+			if f.userId != 0 && f.userId == idea.UserId {
+				followedIdeas = append(followedIdeas, copyIdea(idea))
+			}
+		}
+		if idea.UserId != d.User.Id && m.s.followsTagOf(d.User.Id, idea) {
+			tagIdeas = append(tagIdeas, copyIdea(idea))
+		}
+	}
+	sortNewestFirst(tagIdeas)
+	sortNewestFirst(followedIdeas)
+	d.TagIdeas = tagIdeas[:min(limit, len(tagIdeas))]
+	d.FollowedIdeas = followedIdeas[:min(limit, len(followedIdeas))]
+	return nil
+}
+
+func (s *store) followsTagOf(userId int, idea *data.Idea) bool {
+	for _, f := range s.follows {
+		if f.followerId != userId || f.tagId == 0 {
+			continue
+		}
+		for _, tag := range idea.Tags {
+			if tag.Id == f.tagId {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (m *Digests) MarkSent(ctx context.Context, userId int, sentAt time.Time) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	if u, ok := m.s.users[userId]; ok {
+		u.digestSentAt = &sentAt
+	}
+	return nil
+}
+
+func (m *Digests) SetFrequency(ctx context.Context, userId int, frequency string) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	u, ok := m.s.users[userId]
+	if !ok {
+		return data.ErrNoRows
+	}
+	u.digestFrequency = frequency
+	return nil
+}
@@ -0,0 +1,163 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/sulavmhrzn/projectideas/internal/data"
+)
+
+type Follows struct {
+	s *store
+}
+
+func (s *store) profile(u *user) data.Profile {
+	profile := data.Profile{Id: u.Id, Username: u.Username, CreatedAt: u.CreatedAt}
+	for _, f := range s.follows {
+		if f.userId == u.Id {
+			profile.Followers++
+		}
+		if f.followerId == u.Id && f.userId != 0 {
+			profile.Following++
+		}
+	}
+	return profile
+}
+
+func (m *Follows) Profile(ctx context.Context, userId int) (*data.Profile, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	u, ok := m.s.users[userId]
+	if !ok {
+		return nil, data.ErrNoRows
+	}
+	profile := m.s.profile(u)
+	return &profile, nil
+}
+
+func (m *Follows) Followers(ctx context.Context, userId int) ([]data.Profile, error) {
+	return m.profiles(func(f follow) int {
+		if f.userId == userId {
+			return f.followerId
+		}
+		return 0
+	}), nil
+}
+
+func (m *Follows) Following(ctx context.Context, userId int) ([]data.Profile, error) {
+	return m.profiles(func(f follow) int {
+		if f.followerId == userId {
+			return f.userId
+		}
+		return 0
+	}), nil
+}
+
+// profiles returns the profiles of the users picked from each follow, most
+// recent follow first. pick returns 0 to skip a follow.
+func (m *Follows) profiles(pick func(follow) int) []data.Profile {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	follows := append([]follow(nil), m.s.follows...)
+	sort.SliceStable(follows, func(i, j int) bool {
+		return follows[i].createdAt.After(follows[j].createdAt)
+	})
+	profiles := []data.Profile{}
+	for _, f := range follows {
+		if u, ok := m.s.users[pick(f)]; ok {
+			profiles = append(profiles, m.s.profile(u))
+		}
+	}
+	return profiles
+}
+
+func (m *Follows) FollowedTags(ctx context.Context, userId int) ([]data.Tag, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	tags := []data.Tag{}
+	for _, f := range m.s.follows {
+		if f.followerId != userId || f.tagId == 0 {
+			continue
+		}
+		for _, t := range m.s.tags {
+			if t.Id == f.tagId {
+				tags = append(tags, t)
+			}
+		}
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i].Title < tags[j].Title })
+	return tags, nil
+}
+
+func (m *Follows) FollowUser(ctx context.Context, followerId, userId int) (bool, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	if _, ok := m.s.users[userId]; !ok {
+		return false, data.ErrNoRows
+	}
+	for _, f := range m.s.follows {
+		if f.followerId == followerId && f.userId == userId {
+			return false, nil
+		}
+	}
+	m.s.follows = append(m.s.follows, follow{followerId: followerId, userId: userId, createdAt: time.Now()})
+	return true, nil
+}
+
+func (m *Follows) UnfollowUser(ctx context.Context, followerId, userId int) error {
+	return m.unfollow(func(f follow) bool {
+		return f.followerId == followerId && f.userId == userId
+	})
+}
+
+func (m *Follows) FollowTag(ctx context.Context, followerId int, title string) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	tag, ok := m.s.tagByTitle(title)
+	if !ok {
+		return data.ErrNoRows
+	}
+	for _, f := range m.s.follows {
+		if f.followerId == followerId && f.tagId == tag.Id {
+			return nil
+		}
+	}
+	m.s.follows = append(m.s.follows, follow{followerId: followerId, tagId: tag.Id, createdAt: time.Now()})
+	return nil
+}
+
+func (m *Follows) UnfollowTag(ctx context.Context, followerId int, title string) error {
+	m.s.mu.Lock()
+	tag, ok := m.s.tagByTitle(title)
+	m.s.mu.Unlock()
+	if !ok {
+		return data.ErrNoRows
+	}
+	return m.unfollow(func(f follow) bool {
+		return f.followerId == followerId && f.tagId == tag.Id
+	})
+}
+
+func (m *Follows) unfollow(match func(follow) bool) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	follows := m.s.follows[:0]
+	for _, f := range m.s.follows {
+		if !match(f) {
+			follows = append(follows, f)
+		}
+	}
+	removed := len(m.s.follows) - len(follows)
+	m.s.follows = follows
+	if removed == 0 {
+		return data.ErrNoRows
+	}
+	return nil
+}
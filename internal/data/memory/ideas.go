@@ -0,0 +1,166 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/sulavmhrzn/projectideas/internal/data"
+)
+
+type Ideas struct {
+	s *store
+}
+
+func (m *Ideas) Insert(ctx context.Context, input *data.Idea) (*data.Idea, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	idea := &data.Idea{
+		Id:          m.s.nextId("ideas"),
+		Title:       input.Title,
+		Description: input.Description,
+		UserId:      input.UserId,
+		CreatedAt:   time.Now(),
+	}
+	for _, t := range input.Tags {
+		tag, ok := m.s.tagByTitle(t.Title)
+		if !ok {
+			tag = data.Tag{Id: m.s.nextId("tags"), Title: t.Title}
+			m.s.tags = append(m.s.tags, tag)
+		}
+		idea.Tags = append(idea.Tags, tag)
+	}
+	m.s.ideas[idea.Id] = idea
+
+	inserted := copyIdea(idea)
+	inserted.UserId = 0
+	return &inserted, nil
+}
+
+func (m *Ideas) List(ctx context.Context) ([]data.Idea, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	var ideas []data.Idea
+	for _, idea := range m.s.ideas {
+		if len(idea.Tags) > 0 {
+			ideas = append(ideas, copyIdea(idea))
+		}
+	}
+	sortNewestFirst(ideas)
+	return ideas, nil
+}
+
+func (m *Ideas) ListForUser(ctx context.Context, userId int) ([]data.Idea, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	ideas := []data.Idea{}
+	for _, idea := range m.s.ideas {
+		if idea.UserId == userId {
+			found := copyIdea(idea)
+			if found.Tags == nil {
+				found.Tags = []data.Tag{}
+			}
+			ideas = append(ideas, found)
+		}
+	}
+	sortNewestFirst(ideas)
+	return ideas, nil
+}
+
+func (m *Ideas) Feed(ctx context.Context, userId int, cursor *data.Cursor, limit int) ([]data.Idea, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	ideas := []data.Idea{}
+	for _, idea := range m.s.ideas {
+		if m.s.inFeed(userId, idea) && afterCursor(idea.CreatedAt, idea.Id, cursor) {
+			ideas = append(ideas, copyIdea(idea))
+		}
+	}
+	sortNewestFirst(ideas)
+	if len(ideas) > limit {
+		ideas = ideas[:limit]
+	}
+	return ideas, nil
+}
+
+// inFeed reports whether userId follows the author or one of the tags of idea.
+func (s *store) inFeed(userId int, idea *data.Idea) bool {
+	for _, f := range s.follows {
+		if f.followerId != userId {
+			continue
+		}
+		if f.userId != 0 && f.userId == idea.UserId {
+			return true
+		}
+		for _, tag := range idea.Tags {
+			if f.tagId != 0 && f.tagId == tag.Id {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (m *Ideas) Get(ctx context.Context, id int) (*data.Idea, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	idea, ok := m.s.ideas[id]
+	if !ok || len(idea.Tags) == 0 {
+		return nil, data.ErrNoRows
+	}
+	found := copyIdea(idea)
+	found.UserId = 0
+	return &found, nil
+}
+
+func (m *Ideas) Delete(ctx context.Context, ideaId, userId int) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	idea, ok := m.s.ideas[ideaId]
+	if !ok || idea.UserId != userId {
+		return data.ErrNoRows
+	}
+	m.s.deleteIdea(ideaId)
+	return nil
+}
+
+func (s *store) deleteIdea(id int) {
+	delete(s.ideas, id)
+	notifications := s.notifications[:0]
+	for _, n := range s.notifications {
+		if n.IdeaId != id {
+			notifications = append(notifications, n)
+		}
+	}
+	s.notifications = notifications
+}
+
+// Update changes the title and description of an idea owned by userId,
+// keeping the current values for empty fields. Like the SQL model it does not
+// touch or return tags.
+func (m *Ideas) Update(ctx context.Context, ideaId, userId int, input *data.Idea) (*data.Idea, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	idea, ok := m.s.ideas[ideaId]
+	if !ok || idea.UserId != userId {
+		return nil, data.ErrNoRows
+	}
+	if input.Title != "" {
+		idea.Title = input.Title
+	}
+	if input.Description != "" {
+		idea.Description = input.Description
+	}
+	return &data.Idea{
+		Id:          idea.Id,
+		Title:       idea.Title,
+		Description: idea.Description,
+		CreatedAt:   idea.CreatedAt,
+	}, nil
+}
@@ -0,0 +1,136 @@
+// Package memory implements the data repositories on top of in-memory maps.
+// It mirrors the behaviour of the Postgres models closely enough to exercise
+// handlers in tests without a database.
+package memory
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sulavmhrzn/projectideas/internal/data"
+)
+
+var (
+	_ data.UserRepository         = (*Users)(nil)
+	_ data.TokenRepository        = (*Tokens)(nil)
+	_ data.IdeaRepository         = (*Ideas)(nil)
+	_ data.FollowRepository       = (*Follows)(nil)
+	_ data.NotificationRepository = (*Notifications)(nil)
+	_ data.DigestRepository       = (*Digests)(nil)
+	_ data.AdminRepository        = (*Admin)(nil)
+)
+
+type user struct {
+	data.User
+	ghost           bool
+	deleteAfter     *time.Time
+	reassignIdeas   bool
+	digestFrequency string
+	digestSentAt    *time.Time
+}
+
+type follow struct {
+	followerId int
+	userId     int
+	tagId      int
+	createdAt  time.Time
+}
+
+type store struct {
+	mu            sync.Mutex
+	lastId        map[string]int
+	users         map[int]*user
+	tokens        []data.Token
+	ideas         map[int]*data.Idea
+	tags          []data.Tag
+	follows       []follow
+	notifications []*data.Notification
+	preferences   map[int]map[string]bool
+}
+
+// NewModel returns repositories sharing one empty store, apart from the ghost
+// account that the migrations create as well.
+func NewModel() data.Model {
+	s := &store{
+		lastId:      make(map[string]int),
+		users:       make(map[int]*user),
+		ideas:       make(map[int]*data.Idea),
+		preferences: make(map[int]map[string]bool),
+	}
+	ghost := &user{
+		User: data.User{
+			Username:  data.GhostUsername,
+			Email:     "ghost@localhost",
+			CreatedAt: time.Now(),
+		},
+		ghost:           true,
+		digestFrequency: data.DigestNever,
+	}
+	ghost.Id = s.nextId("users")
+	ghost.Password.HashedPassword = []byte("*")
+	s.users[ghost.Id] = ghost
+
+	return data.Model{
+		User:         &Users{s},
+		Token:        &Tokens{s},
+		Idea:         &Ideas{s},
+		Follow:       &Follows{s},
+		Notification: &Notifications{s},
+		Digest:       &Digests{s},
+		Admin:        &Admin{s},
+	}
+}
+
+func (s *store) nextId(table string) int {
+	s.lastId[table]++
+	return s.lastId[table]
+}
+
+func (s *store) ghost() *user {
+	for _, u := range s.users {
+		if u.ghost {
+			return u
+		}
+	}
+	return nil
+}
+
+func (s *store) tagByTitle(title string) (data.Tag, bool) {
+	for _, t := range s.tags {
+		if t.Title == title {
+			return t, true
+		}
+	}
+	return data.Tag{}, false
+}
+
+// copyIdea returns a copy of idea that does not share its tags slice.
+func copyIdea(idea *data.Idea) data.Idea {
+	c := *idea
+	c.Tags = append([]data.Tag(nil), idea.Tags...)
+	return c
+}
+
+// sortNewestFirst orders ideas the way the SQL queries do, by creation time
+// and then id, both descending.
+func sortNewestFirst(ideas []data.Idea) {
+	sort.Slice(ideas, func(i, j int) bool {
+		return newer(ideas[i].CreatedAt, ideas[i].Id, ideas[j].CreatedAt, ideas[j].Id)
+	})
+}
+
+func newer(aTime time.Time, aId int, bTime time.Time, bId int) bool {
+	if !aTime.Equal(bTime) {
+		return aTime.After(bTime)
+	}
+	return aId > bId
+}
+
+// afterCursor reports whether a row comes after cursor in newest first order.
+func afterCursor(createdAt time.Time, id int, cursor *data.Cursor) bool {
+	if cursor == nil {
+		return true
+	}
+	return newer(cursor.CreatedAt, cursor.Id, createdAt, id)
+}
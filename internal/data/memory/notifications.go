@@ -0,0 +1,88 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/sulavmhrzn/projectideas/internal/data"
+)
+
+type Notifications struct {
+	s *store
+}
+
+func (m *Notifications) Insert(ctx context.Context, n *data.Notification) (bool, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	if enabled, ok := m.s.preferences[n.UserId][n.Event]; ok && !enabled {
+		return false, nil
+	}
+	n.Id = m.s.nextId("notifications")
+	n.CreatedAt = time.Now()
+	stored := *n
+	m.s.notifications = append(m.s.notifications, &stored)
+	return true, nil
+}
+
+func (m *Notifications) List(ctx context.Context, userId int, unreadOnly bool, cursor *data.Cursor, limit int) ([]data.Notification, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	notifications := []data.Notification{}
+	for i := len(m.s.notifications) - 1; i >= 0 && len(notifications) < limit; i-- {
+		n := m.s.notifications[i]
+		if n.UserId != userId || (unreadOnly && n.ReadAt != nil) || !afterCursor(n.CreatedAt, n.Id, cursor) {
+			continue
+		}
+		notifications = append(notifications, *n)
+	}
+	return notifications, nil
+}
+
+func (m *Notifications) MarkRead(ctx context.Context, userId int, ids []int) (int64, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	now := time.Now()
+	var updated int64
+	for _, n := range m.s.notifications {
+		if n.UserId != userId || n.ReadAt != nil {
+			continue
+		}
+		if len(ids) > 0 && !slices.Contains(ids, n.Id) {
+			continue
+		}
+		n.ReadAt = &now
+		updated++
+	}
+	return updated, nil
+}
+
+func (m *Notifications) Preferences(ctx context.Context, userId int) (map[string]bool, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	preferences := make(map[string]bool, len(data.Events))
+	for _, event := range data.Events {
+		preferences[event] = true
+	}
+	for event, enabled := range m.s.preferences[userId] {
+		preferences[event] = enabled
+	}
+	return preferences, nil
+}
+
+func (m *Notifications) SetPreferences(ctx context.Context, userId int, preferences map[string]bool) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	if m.s.preferences[userId] == nil {
+		m.s.preferences[userId] = make(map[string]bool)
+	}
+	for event, enabled := range preferences {
+		m.s.preferences[userId][event] = enabled
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/sulavmhrzn/projectideas/internal/data"
+)
+
+type Tokens struct {
+	s *store
+}
+
+func (m *Tokens) New(ctx context.Context, userId int, ttl time.Duration, scope string) (*data.Token, error) {
+	token, err := data.GenerateToken(userId, ttl, scope)
+	if err != nil {
+		return nil, err
+	}
+	err = m.Insert(ctx, token)
+	return token, err
+}
+
+func (m *Tokens) Insert(ctx context.Context, token *data.Token) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	// tokens.token is the primary key.
+	for _, t := range m.s.tokens {
+		if t.Token == token.Token {
+			return fmt.Errorf("duplicate token %q", token.Token)
+		}
+	}
+	m.s.tokens = append(m.s.tokens, *token)
+	return nil
+}
+
+func (m *Tokens) DeleteForUser(ctx context.Context, id int) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	tokens := m.s.tokens[:0]
+	for _, t := range m.s.tokens {
+		if t.UserId != id {
+			tokens = append(tokens, t)
+		}
+	}
+	m.s.tokens = tokens
+	return nil
+}
+
+func (m *Tokens) ListForUser(ctx context.Context, id int) ([]data.Token, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	var tokens []data.Token
+	for _, t := range m.s.tokens {
+		if t.UserId == id {
+			tokens = append(tokens, t)
+		}
+	}
+	sort.Slice(tokens, func(i, j int) bool {
+		return tokens[i].ExpiresAt.After(tokens[j].ExpiresAt)
+	})
+	return tokens, nil
+}
+
+func (m *Tokens) CountActive(ctx context.Context) (map[string]int, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	counts := map[string]int{data.ScopeAuthentication: 0, data.ScopePasswordReset: 0}
+	for _, t := range m.s.tokens {
+		if t.ExpiresAt.After(time.Now()) {
+			counts[t.Scope]++
+		}
+	}
+	return counts, nil
+}
+
+func (m *Tokens) DeleteExpired(ctx context.Context, limit int) (int64, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	var deleted int64
+	tokens := m.s.tokens[:0]
+	for _, t := range m.s.tokens {
+		if deleted < int64(limit) && !t.ExpiresAt.After(time.Now()) {
+			deleted++
+			continue
+		}
+		tokens = append(tokens, t)
+	}
+	m.s.tokens = tokens
+	return deleted, nil
+}
@@ -0,0 +1,208 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/sulavmhrzn/projectideas/internal/data"
+)
+
+type Users struct {
+	s *store
+}
+
+func (m *Users) Insert(ctx context.Context, u *data.User) (*data.User, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	for _, existing := range m.s.users {
+		switch {
+		case existing.Username == u.Username:
+			return nil, data.ErrDuplicateUsername
+		case existing.Email == u.Email:
+			return nil, data.ErrDuplicateEmail
+		}
+	}
+	u.Id = m.s.nextId("users")
+	u.CreatedAt = time.Now()
+	stored := &user{User: *u, digestFrequency: data.DigestNever}
+	stored.Password.PlainPassword = ""
+	m.s.users[u.Id] = stored
+	return u, nil
+}
+
+func (m *Users) UpdatePassword(ctx context.Context, id int, hashed_password []byte) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	if u, ok := m.s.users[id]; ok {
+		u.Password.HashedPassword = hashed_password
+	}
+	return nil
+}
+
+func (m *Users) GetByEmail(ctx context.Context, email string) (*data.User, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	for _, u := range m.s.users {
+		if u.Email == email {
+			found := u.User
+			return &found, nil
+		}
+	}
+	return nil, data.ErrNoRows
+}
+
+func (m *Users) GetForToken(ctx context.Context, token string, scope string) (*data.User, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	for _, t := range m.s.tokens {
+		if t.Token != token || t.Scope != scope || !t.ExpiresAt.After(time.Now()) {
+			continue
+		}
+		u, ok := m.s.users[t.UserId]
+		if !ok || u.Banned() {
+			break
+		}
+		found := u.User
+		found.Password = data.User{}.Password
+		return &found, nil
+	}
+	return nil, data.ErrNoRows
+}
+
+func (m *Users) List(ctx context.Context) ([]data.User, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	var users []data.User
+	for _, u := range m.s.users {
+		found := u.User
+		found.Password = data.User{}.Password
+		users = append(users, found)
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].Id < users[j].Id })
+	return users, nil
+}
+
+func (m *Users) SetAdmin(ctx context.Context, id int, admin bool) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	u, ok := m.s.users[id]
+	if !ok {
+		return data.ErrNoRows
+	}
+	u.IsAdmin = admin
+	return nil
+}
+
+func (m *Users) SetBanned(ctx context.Context, id int, banned bool) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	u, ok := m.s.users[id]
+	if !ok {
+		return data.ErrNoRows
+	}
+	switch {
+	case !banned:
+		u.BannedAt = nil
+	case u.BannedAt == nil:
+		now := time.Now()
+		u.BannedAt = &now
+	}
+	return nil
+}
+
+func (m *Users) ScheduleDeletion(ctx context.Context, id int, deleteAfter time.Time, reassignIdeas bool) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	u, ok := m.s.users[id]
+	if !ok {
+		return data.ErrNoRows
+	}
+	u.deleteAfter = &deleteAfter
+	u.reassignIdeas = reassignIdeas
+	return nil
+}
+
+func (m *Users) CancelDeletion(ctx context.Context, id int) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	u, ok := m.s.users[id]
+	if !ok || u.deleteAfter == nil {
+		return data.ErrNoRows
+	}
+	u.deleteAfter = nil
+	u.reassignIdeas = false
+	return nil
+}
+
+func (m *Users) DeleteScheduled(ctx context.Context) (int64, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	ghost := m.s.ghost()
+	if ghost == nil {
+		return 0, data.ErrNoGhostAccount
+	}
+	var deleted int64
+	for id, u := range m.s.users {
+		if u.deleteAfter == nil || u.deleteAfter.After(time.Now()) {
+			continue
+		}
+		for ideaId, idea := range m.s.ideas {
+			if idea.UserId != id {
+				continue
+			}
+			if u.reassignIdeas {
+				idea.UserId = ghost.Id
+			} else {
+				m.s.deleteIdea(ideaId)
+			}
+		}
+		m.s.deleteUser(id)
+		deleted++
+	}
+	return deleted, nil
+}
+
+// deleteUser removes a user and cascades like the foreign keys do.
+func (s *store) deleteUser(id int) {
+	delete(s.users, id)
+	delete(s.preferences, id)
+
+	tokens := s.tokens[:0]
+	for _, t := range s.tokens {
+		if t.UserId != id {
+			tokens = append(tokens, t)
+		}
+	}
+	s.tokens = tokens
+
+	follows := s.follows[:0]
+	for _, f := range s.follows {
+		if f.followerId != id && f.userId != id {
+			follows = append(follows, f)
+		}
+	}
+	s.follows = follows
+
+	notifications := s.notifications[:0]
+	for _, n := range s.notifications {
+		if n.UserId == id {
+			continue
+		}
+		if n.ActorId == id {
+			n.ActorId = 0
+		}
+		notifications = append(notifications, n)
+	}
+	s.notifications = notifications
+}
@@ -0,0 +1,53 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sulavmhrzn/projectideas/internal/data"
+)
+
+func TestDeleteScheduled(t *testing.T) {
+	ctx := context.Background()
+	setup := func(t *testing.T) (data.Model, *store, *data.Idea) {
+		m := NewModel()
+		u, err := m.User.Insert(ctx, &data.User{Username: "alice", Email: "alice@example.com"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		idea, err := m.Idea.Insert(ctx, &data.Idea{Title: "Idea", UserId: u.Id, Tags: []data.Tag{{Title: "go"}}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = m.User.ScheduleDeletion(ctx, u.Id, time.Now().Add(-time.Minute), true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return m, m.User.(*Users).s, idea
+	}
+
+	t.Run("reassigns ideas to the ghost", func(t *testing.T) {
+		m, s, idea := setup(t)
+		deleted, err := m.User.DeleteScheduled(ctx)
+		if err != nil || deleted != 1 {
+			t.Fatalf("deleted %d, err %v", deleted, err)
+		}
+		if got := s.ideas[idea.Id]; got == nil || got.UserId != s.ghost().Id {
+			t.Errorf("idea %+v is not owned by the ghost", got)
+		}
+	})
+
+	t.Run("fails without a ghost", func(t *testing.T) {
+		m, s, idea := setup(t)
+		delete(s.users, s.ghost().Id)
+		deleted, err := m.User.DeleteScheduled(ctx)
+		if !errors.Is(err, data.ErrNoGhostAccount) || deleted != 0 {
+			t.Fatalf("deleted %d, err %v, want %v", deleted, err, data.ErrNoGhostAccount)
+		}
+		if len(s.users) != 1 || s.ideas[idea.Id] == nil {
+			t.Error("the user or their idea was deleted")
+		}
+	})
+}
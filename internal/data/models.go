@@ -1,26 +1,104 @@
 package data
 
 import (
+	"context"
 	"database/sql"
 	"errors"
+	"time"
 )
 
 var (
 	ErrDuplicateUsername = errors.New("duplicate username")
 	ErrDuplicateEmail    = errors.New("duplicate email")
 	ErrNoRows            = errors.New("no rows found")
+	ErrNoGhostAccount    = errors.New("ghost account not found")
 )
 
+type UserRepository interface {
+	Insert(ctx context.Context, user *User) (*User, error)
+	UpdatePassword(ctx context.Context, id int, hashed_password []byte) error
+	GetByEmail(ctx context.Context, email string) (*User, error)
+	GetForToken(ctx context.Context, token string, scope string) (*User, error)
+	ScheduleDeletion(ctx context.Context, id int, deleteAfter time.Time, reassignIdeas bool) error
+	CancelDeletion(ctx context.Context, id int) error
+	DeleteScheduled(ctx context.Context) (int64, error)
+	List(ctx context.Context) ([]User, error)
+	SetAdmin(ctx context.Context, id int, admin bool) error
+	SetBanned(ctx context.Context, id int, banned bool) error
+}
+
+type TokenRepository interface {
+	New(ctx context.Context, userId int, ttl time.Duration, scope string) (*Token, error)
+	Insert(ctx context.Context, token *Token) error
+	DeleteForUser(ctx context.Context, id int) error
+	ListForUser(ctx context.Context, id int) ([]Token, error)
+	CountActive(ctx context.Context) (map[string]int, error)
+	DeleteExpired(ctx context.Context, limit int) (int64, error)
+}
+
+type IdeaRepository interface {
+	Insert(ctx context.Context, input *Idea) (*Idea, error)
+	List(ctx context.Context) ([]Idea, error)
+	ListForUser(ctx context.Context, userId int) ([]Idea, error)
+	Feed(ctx context.Context, userId int, cursor *Cursor, limit int) ([]Idea, error)
+	Get(ctx context.Context, id int) (*Idea, error)
+	Delete(ctx context.Context, ideaId, userId int) error
+	Update(ctx context.Context, ideaId, userId int, input *Idea) (*Idea, error)
+}
+
+type FollowRepository interface {
+	Profile(ctx context.Context, userId int) (*Profile, error)
+	Followers(ctx context.Context, userId int) ([]Profile, error)
+	Following(ctx context.Context, userId int) ([]Profile, error)
+	FollowedTags(ctx context.Context, userId int) ([]Tag, error)
+	FollowUser(ctx context.Context, followerId, userId int) (bool, error)
+	UnfollowUser(ctx context.Context, followerId, userId int) error
+	FollowTag(ctx context.Context, followerId int, title string) error
+	UnfollowTag(ctx context.Context, followerId int, title string) error
+}
+
+type NotificationRepository interface {
+	Insert(ctx context.Context, n *Notification) (bool, error)
+	List(ctx context.Context, userId int, unreadOnly bool, cursor *Cursor, limit int) ([]Notification, error)
+	MarkRead(ctx context.Context, userId int, ids []int) (int64, error)
+	Preferences(ctx context.Context, userId int) (map[string]bool, error)
+	SetPreferences(ctx context.Context, userId int, preferences map[string]bool) error
+}
+
+type DigestRepository interface {
+	Due(ctx context.Context) ([]Digest, error)
+	Fill(ctx context.Context, d *Digest, limit int) error
+	MarkSent(ctx context.Context, userId int, sentAt time.Time) error
+	SetFrequency(ctx context.Context, userId int, frequency string) error
+}
+
+type AdminRepository interface {
+	MergeTags(ctx context.Context, from, into string) (int64, error)
+	CheckIntegrity(ctx context.Context) (*IntegrityReport, error)
+	RepairIntegrity(ctx context.Context) (*IntegrityReport, error)
+	Stats(ctx context.Context) (*Stats, error)
+}
+
 type Model struct {
-	User  UserModel
-	Token TokenModel
-	Idea  IdeaModel
+	User         UserRepository
+	Token        TokenRepository
+	Idea         IdeaRepository
+	Follow       FollowRepository
+	Notification NotificationRepository
+	Digest       DigestRepository
+	Admin        AdminRepository
 }
 
-func NewModel(db *sql.DB) Model {
+// NewModel returns Postgres backed models whose queries are cancelled after
+// queryTimeout, or earlier if the context passed to them is done.
+func NewModel(db *sql.DB, queryTimeout time.Duration) Model {
 	return Model{
-		User:  UserModel{DB: db},
-		Token: TokenModel{DB: db},
-		Idea:  IdeaModel{DB: db},
+		User:         UserModel{DB: db, QueryTimeout: queryTimeout},
+		Token:        &TokenModel{DB: db, QueryTimeout: queryTimeout},
+		Idea:         IdeaModel{DB: db, QueryTimeout: queryTimeout},
+		Follow:       FollowModel{DB: db, QueryTimeout: queryTimeout},
+		Notification: NotificationModel{DB: db, QueryTimeout: queryTimeout},
+		Digest:       DigestModel{DB: db, QueryTimeout: queryTimeout},
+		Admin:        AdminModel{DB: db, QueryTimeout: queryTimeout},
 	}
 }
@@ -0,0 +1,172 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	EventFollow = "follow"
+	// EventModeration is sent when an admin lifts a ban on the user.
+	EventModeration = "moderation"
+)
+
+// Events lists every notification event a user can opt out of.
+var Events = []string{EventFollow, EventModeration}
+
+type Notification struct {
+	Id        int        `json:"id"`
+	UserId    int        `json:"-"`
+	ActorId   int        `json:"actor_id,omitempty"`
+	IdeaId    int        `json:"idea_id,omitempty"`
+	Event     string     `json:"event"`
+	ReadAt    *time.Time `json:"read_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+type NotificationModel struct {
+	DB           *sql.DB
+	QueryTimeout time.Duration
+}
+
+// Insert stores the notification unless its recipient has turned the event
+// off. It reports whether the notification was stored.
+func (m NotificationModel) Insert(ctx context.Context, n *Notification) (bool, error) {
+	query := `
+	INSERT INTO notifications (user_id, actor_id, idea_id, event)
+	SELECT $1, NULLIF($2, 0), NULLIF($3, 0), $4
+	WHERE NOT EXISTS (
+		SELECT 1 FROM notification_preferences
+		WHERE user_id = $1 AND event = $4 AND NOT enabled
+	)
+	RETURNING id, created_at`
+	ctx, cancel := context.WithTimeout(ctx, m.QueryTimeout)
+	defer cancel()
+
+	args := []any{n.UserId, n.ActorId, n.IdeaId, n.Event}
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&n.Id, &n.CreatedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return false, nil
+		default:
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// List returns up to limit notifications of userId, newest first, starting
+// after cursor.
+func (m NotificationModel) List(ctx context.Context, userId int, unreadOnly bool, cursor *Cursor, limit int) ([]Notification, error) {
+	query := `
+	SELECT id, user_id, COALESCE(actor_id, 0), COALESCE(idea_id, 0), event, read_at, created_at
+	FROM notifications
+	WHERE user_id = $1
+	AND (NOT $2 OR read_at IS NULL)
+	AND ($3::timestamptz IS NULL OR (created_at, id) < ($3, $4))
+	ORDER BY created_at DESC, id DESC
+	LIMIT $5`
+	ctx, cancel := context.WithTimeout(ctx, m.QueryTimeout)
+	defer cancel()
+
+	createdAt, id := cursorArgs(cursor)
+	rows, err := m.DB.QueryContext(ctx, query, userId, unreadOnly, createdAt, id, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	notifications := []Notification{}
+	for rows.Next() {
+		var n Notification
+		err := rows.Scan(&n.Id, &n.UserId, &n.ActorId, &n.IdeaId, &n.Event, &n.ReadAt, &n.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		notifications = append(notifications, n)
+	}
+	return notifications, rows.Err()
+}
+
+// MarkRead marks the given notifications of userId as read, or all of them
+// when ids is empty. It returns the number of notifications updated.
+func (m NotificationModel) MarkRead(ctx context.Context, userId int, ids []int) (int64, error) {
+	query := `
+	UPDATE notifications
+	SET read_at = now()
+	WHERE user_id = $1
+	AND read_at IS NULL
+	AND (cardinality($2::int[]) = 0 OR id = ANY($2))`
+	ctx, cancel := context.WithTimeout(ctx, m.QueryTimeout)
+	defer cancel()
+
+	idArray := make([]int64, 0, len(ids))
+	for _, id := range ids {
+		idArray = append(idArray, int64(id))
+	}
+	result, err := m.DB.ExecContext(ctx, query, userId, pq.Array(idArray))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// Preferences returns whether userId receives each event. Events without a
+// stored preference are enabled.
+func (m NotificationModel) Preferences(ctx context.Context, userId int) (map[string]bool, error) {
+	query := `
+	SELECT event, enabled
+	FROM notification_preferences
+	WHERE user_id = $1`
+	ctx, cancel := context.WithTimeout(ctx, m.QueryTimeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	preferences := make(map[string]bool, len(Events))
+	for _, event := range Events {
+		preferences[event] = true
+	}
+	for rows.Next() {
+		var event string
+		var enabled bool
+		err := rows.Scan(&event, &enabled)
+		if err != nil {
+			return nil, err
+		}
+		preferences[event] = enabled
+	}
+	return preferences, rows.Err()
+}
+
+func (m NotificationModel) SetPreferences(ctx context.Context, userId int, preferences map[string]bool) error {
+	query := `
+	INSERT INTO notification_preferences (user_id, event, enabled)
+	VALUES ($1, $2, $3)
+	ON CONFLICT (user_id, event) DO UPDATE SET enabled = EXCLUDED.enabled`
+	ctx, cancel := context.WithTimeout(ctx, m.QueryTimeout)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for event, enabled := range preferences {
+		_, err := tx.ExecContext(ctx, query, userId, event, enabled)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
@@ -21,10 +21,11 @@ type Token struct {
 }
 
 type TokenModel struct {
-	DB *sql.DB
+	DB           *sql.DB
+	QueryTimeout time.Duration
 }
 
-func generateToken(userId int, ttl time.Duration, scope string) (*Token, error) {
+func GenerateToken(userId int, ttl time.Duration, scope string) (*Token, error) {
 	randomBytes := make([]byte, 16)
 	_, err := rand.Read(randomBytes)
 	if err != nil {
@@ -40,33 +41,109 @@ func generateToken(userId int, ttl time.Duration, scope string) (*Token, error)
 	return token, nil
 }
 
-func (m *TokenModel) New(userId int, ttl time.Duration, scope string) (*Token, error) {
-	token, err := generateToken(userId, ttl, scope)
+func (m *TokenModel) New(ctx context.Context, userId int, ttl time.Duration, scope string) (*Token, error) {
+	token, err := GenerateToken(userId, ttl, scope)
 	if err != nil {
 		return nil, err
 	}
-	err = m.Insert(token)
+	err = m.Insert(ctx, token)
 	return token, err
 }
 
-func (m *TokenModel) Insert(token *Token) error {
+func (m *TokenModel) Insert(ctx context.Context, token *Token) error {
 	query := `INSERT INTO tokens 
 	(userId, token, scope, expires_at)
 	VALUES 
 	($1, $2, $3, $4)`
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, m.QueryTimeout)
 	defer cancel()
 	args := []any{token.UserId, token.Token, token.Scope, token.ExpiresAt}
 	_, err := m.DB.ExecContext(ctx, query, args...)
 	return err
 }
 
-func (m *TokenModel) DeleteForUser(id int) error {
+func (m *TokenModel) DeleteForUser(ctx context.Context, id int) error {
 	query := `
 	DELETE FROM tokens
 	WHERE tokens.userId = $1`
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, m.QueryTimeout)
 	defer cancel()
 	_, err := m.DB.ExecContext(ctx, query, id)
 	return err
 }
+
+func (m *TokenModel) ListForUser(ctx context.Context, id int) ([]Token, error) {
+	query := `
+	SELECT userId, token, scope, expires_at
+	FROM tokens
+	WHERE userId = $1
+	ORDER BY expires_at DESC`
+	ctx, cancel := context.WithTimeout(ctx, m.QueryTimeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tokens []Token
+	for rows.Next() {
+		var token Token
+		err := rows.Scan(&token.UserId, &token.Token, &token.Scope, &token.ExpiresAt)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, rows.Err()
+}
+
+// CountActive returns the number of unexpired tokens per scope.
+func (m *TokenModel) CountActive(ctx context.Context) (map[string]int, error) {
+	query := `
+	SELECT scope, count(*)
+	FROM tokens
+	WHERE expires_at > now()
+	GROUP BY scope`
+	ctx, cancel := context.WithTimeout(ctx, m.QueryTimeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := map[string]int{ScopeAuthentication: 0, ScopePasswordReset: 0}
+	for rows.Next() {
+		var scope string
+		var count int
+		err := rows.Scan(&scope, &count)
+		if err != nil {
+			return nil, err
+		}
+		counts[scope] = count
+	}
+	return counts, rows.Err()
+}
+
+// DeleteExpired removes up to limit expired tokens and returns how many were
+// deleted, so that large backlogs can be cleared in short transactions.
+func (m *TokenModel) DeleteExpired(ctx context.Context, limit int) (int64, error) {
+	query := `
+	DELETE FROM tokens
+	WHERE token IN (
+		SELECT token FROM tokens
+		WHERE expires_at <= now()
+		LIMIT $1
+	)`
+	ctx, cancel := context.WithTimeout(ctx, m.QueryTimeout)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, limit)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -12,20 +12,32 @@ import (
 
 var AnonymousUser = &User{}
 
+// GhostUsername is the username of the account that takes over the ideas of
+// users who chose to keep them when deleting their account. The account is
+// marked by the is_ghost column rather than found by this name.
+const GhostUsername = "ghost"
+
 type User struct {
-	Id        int       `json:"id"`
-	Username  string    `json:"username"`
-	Email     string    `json:"email"`
-	Password  password  `json:"-"`
-	CreatedAt time.Time `json:"created_at"`
+	Id        int        `json:"id"`
+	Username  string     `json:"username"`
+	Email     string     `json:"email"`
+	Password  password   `json:"-"`
+	CreatedAt time.Time  `json:"created_at"`
+	IsAdmin   bool       `json:"-"`
+	BannedAt  *time.Time `json:"-"`
 }
 
 func (u *User) IsAnonymousUser() bool {
 	return u == AnonymousUser
 }
 
+func (u *User) Banned() bool {
+	return u.BannedAt != nil
+}
+
 type UserModel struct {
-	DB *sql.DB
+	DB           *sql.DB
+	QueryTimeout time.Duration
 }
 
 type password struct {
@@ -49,24 +61,26 @@ func (p *password) Compare(plainPassword string) bool {
 }
 
 func ValidateUser(v *validator.Validator, user *User) {
-	v.Check(user.Username != "", "username", "must be provided")
-	v.Check(len(user.Username) <= 10, "username", "must not be greater than 10 characters long")
-	v.Check(user.Email != "", "email", "must be provided")
-	v.Check(validator.ValidEmail(user.Email), "email", "must be a valid email address")
-	v.Check(user.Password.PlainPassword != "", "password", "must be provided")
-	v.Check(len(user.Password.PlainPassword) < 72, "password", "must not be greater than 72 characters long")
-	v.Check(len(user.Password.PlainPassword) > 10, "password", "must be greater than 10 characters long")
+	v.Check(user.Username != "", "username", validator.Required)
+	v.Check(validator.MaxRunes(user.Username, 10), "username", validator.TooLong, 10)
+	v.Check(user.Email != "", "email", validator.Required)
+	v.Check(validator.ValidEmail(user.Email), "email", validator.InvalidEmail)
+	v.Check(user.Password.PlainPassword != "", "password", validator.Required)
+	// bcrypt only uses the first 72 bytes of a password, whatever its length
+	// in characters.
+	v.Check(len(user.Password.PlainPassword) < 72, "password", validator.TooManyBytes, 71)
+	v.Check(validator.MinRunes(user.Password.PlainPassword, 11), "password", validator.TooShort, 11)
 }
 
-func (m UserModel) Insert(user *User) (*User, error) {
+func (m UserModel) Insert(ctx context.Context, user *User) (*User, error) {
 	query := `INSERT INTO users 
-	(username, email, hash_password)
+	(username, email, hash_password, is_admin)
 	VALUES 
-	($1, $2, $3)
+	($1, $2, $3, $4)
 	RETURNING id, created_at`
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, m.QueryTimeout)
 	defer cancel()
-	args := []any{user.Username, user.Email, user.Password.HashedPassword}
+	args := []any{user.Username, user.Email, user.Password.HashedPassword, user.IsAdmin}
 	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&user.Id, &user.CreatedAt)
 	if err != nil {
 		switch {
@@ -81,12 +95,12 @@ func (m UserModel) Insert(user *User) (*User, error) {
 	return user, nil
 }
 
-func (m UserModel) UpdatePassword(id int, hashed_password []byte) error {
+func (m UserModel) UpdatePassword(ctx context.Context, id int, hashed_password []byte) error {
 	query := `
 	UPDATE users
 	SET hash_password = $1
 	WHERE id = $2`
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, m.QueryTimeout)
 	defer cancel()
 
 	_, err := m.DB.ExecContext(ctx, query, hashed_password, id)
@@ -96,15 +110,15 @@ func (m UserModel) UpdatePassword(id int, hashed_password []byte) error {
 	return nil
 }
 
-func (m UserModel) GetByEmail(email string) (*User, error) {
+func (m UserModel) GetByEmail(ctx context.Context, email string) (*User, error) {
 	query := `
-	SELECT id, username, email, hash_password
+	SELECT id, username, email, hash_password, created_at, is_admin, banned_at
 	FROM users
 	WHERE email = $1`
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, m.QueryTimeout)
 	defer cancel()
 	var user User
-	err := m.DB.QueryRowContext(ctx, query, email).Scan(&user.Id, &user.Username, &user.Email, &user.Password.HashedPassword)
+	err := m.DB.QueryRowContext(ctx, query, email).Scan(&user.Id, &user.Username, &user.Email, &user.Password.HashedPassword, &user.CreatedAt, &user.IsAdmin, &user.BannedAt)
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
@@ -116,19 +130,20 @@ func (m UserModel) GetByEmail(email string) (*User, error) {
 	return &user, nil
 }
 
-func (m UserModel) GetForToken(token string, scope string) (*User, error) {
+func (m UserModel) GetForToken(ctx context.Context, token string, scope string) (*User, error) {
 	query := `
-	SELECT id, username, email, created_at 
+	SELECT id, username, email, created_at, is_admin
 	FROM users
 	JOIN tokens
 	ON tokens.userId = users.id
 	WHERE tokens.token = $1
 	AND tokens.scope = $2
-	AND expires_at > now()`
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	AND expires_at > now()
+	AND banned_at IS NULL`
+	ctx, cancel := context.WithTimeout(ctx, m.QueryTimeout)
 	defer cancel()
 	var user User
-	err := m.DB.QueryRowContext(ctx, query, token, scope).Scan(&user.Id, &user.Username, &user.Email, &user.CreatedAt)
+	err := m.DB.QueryRowContext(ctx, query, token, scope).Scan(&user.Id, &user.Username, &user.Email, &user.CreatedAt, &user.IsAdmin)
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
@@ -139,3 +154,150 @@ func (m UserModel) GetForToken(token string, scope string) (*User, error) {
 	}
 	return &user, nil
 }
+
+// List returns every user ordered by id, for administration.
+func (m UserModel) List(ctx context.Context) ([]User, error) {
+	query := `
+	SELECT id, username, email, created_at, is_admin, banned_at
+	FROM users
+	ORDER BY id`
+	ctx, cancel := context.WithTimeout(ctx, m.QueryTimeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []User
+	for rows.Next() {
+		var user User
+		err := rows.Scan(&user.Id, &user.Username, &user.Email, &user.CreatedAt, &user.IsAdmin, &user.BannedAt)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	return users, rows.Err()
+}
+
+func (m UserModel) SetAdmin(ctx context.Context, id int, admin bool) error {
+	query := `
+	UPDATE users
+	SET is_admin = $1
+	WHERE id = $2`
+	return m.update(ctx, query, admin, id)
+}
+
+// SetBanned bans or unbans a user. Banned users cannot log in and their
+// tokens stop working; banning again keeps the original ban time.
+func (m UserModel) SetBanned(ctx context.Context, id int, banned bool) error {
+	query := `
+	UPDATE users
+	SET banned_at = CASE WHEN $1 THEN coalesce(banned_at, now()) END
+	WHERE id = $2`
+	return m.update(ctx, query, banned, id)
+}
+
+func (m UserModel) update(ctx context.Context, query string, args ...any) error {
+	ctx, cancel := context.WithTimeout(ctx, m.QueryTimeout)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrNoRows
+	}
+	return nil
+}
+
+func (m UserModel) ScheduleDeletion(ctx context.Context, id int, deleteAfter time.Time, reassignIdeas bool) error {
+	query := `
+	UPDATE users
+	SET delete_after = $1, reassign_ideas = $2
+	WHERE id = $3`
+	ctx, cancel := context.WithTimeout(ctx, m.QueryTimeout)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, deleteAfter, reassignIdeas, id)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrNoRows
+	}
+	return nil
+}
+
+// CancelDeletion keeps an account that was scheduled for deletion. It returns
+// ErrNoRows when no deletion is pending.
+func (m UserModel) CancelDeletion(ctx context.Context, id int) error {
+	query := `
+	UPDATE users
+	SET delete_after = NULL, reassign_ideas = false
+	WHERE id = $1
+	AND delete_after IS NOT NULL`
+	return m.update(ctx, query, id)
+}
+
+// DeleteScheduled removes every user whose grace period has passed. Ideas of
+// users who asked for them to be kept are handed over to the ghost account,
+// the rest are removed along with the user. Nothing is deleted if the ghost
+// account is missing.
+func (m UserModel) DeleteScheduled(ctx context.Context) (int64, error) {
+	ghostQuery := `
+	SELECT id FROM users
+	WHERE is_ghost`
+	reassignQuery := `
+	UPDATE ideas
+	SET user_id = $1
+	WHERE user_id IN (
+		SELECT id FROM users
+		WHERE delete_after <= now()
+		AND reassign_ideas
+	)`
+	deleteQuery := `
+	DELETE FROM users
+	WHERE delete_after <= now()`
+	ctx, cancel := context.WithTimeout(ctx, m.QueryTimeout)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var ghostId int
+	err = tx.QueryRowContext(ctx, ghostQuery).Scan(&ghostId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrNoGhostAccount
+		}
+		return 0, err
+	}
+	_, err = tx.ExecContext(ctx, reassignQuery, ghostId)
+	if err != nil {
+		return 0, err
+	}
+	result, err := tx.ExecContext(ctx, deleteQuery)
+	if err != nil {
+		return 0, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return rowsAffected, tx.Commit()
+}
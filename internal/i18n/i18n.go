@@ -0,0 +1,124 @@
+// Package i18n translates messages identified by stable codes into the
+// languages that have a catalog in the locales directory.
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Fallback is used for languages without a catalog and for codes missing
+// from a catalog.
+const Fallback = "en"
+
+//go:embed locales/*.json
+var locales embed.FS
+
+// catalogs maps a language to its messages keyed by code. Messages refer to
+// their arguments as {0}, {1} and so on.
+var catalogs = mustLoad()
+
+func mustLoad() map[string]map[string]string {
+	files, err := locales.ReadDir("locales")
+	if err != nil {
+		panic(err)
+	}
+	catalogs := make(map[string]map[string]string)
+	for _, f := range files {
+		b, err := locales.ReadFile(path.Join("locales", f.Name()))
+		if err != nil {
+			panic(err)
+		}
+		var messages map[string]string
+		if err := json.Unmarshal(b, &messages); err != nil {
+			panic(fmt.Sprintf("i18n: %s: %v", f.Name(), err))
+		}
+		catalogs[strings.TrimSuffix(f.Name(), ".json")] = messages
+	}
+	if _, ok := catalogs[Fallback]; !ok {
+		panic("i18n: no catalog for the fallback language")
+	}
+	return catalogs
+}
+
+// Languages returns the languages that have a catalog.
+func Languages() []string {
+	langs := make([]string, 0, len(catalogs))
+	for lang := range catalogs {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// Codes returns the codes in the catalog of lang.
+func Codes(lang string) []string {
+	codes := make([]string, 0, len(catalogs[lang]))
+	for code := range catalogs[lang] {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// Translate returns the message for code in lang with its placeholders
+// replaced by args. It falls back to English, then to the code itself.
+func Translate(lang, code string, args ...any) string {
+	message, ok := catalogs[lang][code]
+	if !ok {
+		message, ok = catalogs[Fallback][code]
+	}
+	if !ok {
+		return code
+	}
+	if len(args) == 0 {
+		return message
+	}
+	pairs := make([]string, 0, 2*len(args))
+	for i, arg := range args {
+		pairs = append(pairs, "{"+strconv.Itoa(i)+"}", fmt.Sprint(arg))
+	}
+	return strings.NewReplacer(pairs...).Replace(message)
+}
+
+// Negotiate picks the language with a catalog that best matches an
+// Accept-Language header, trying each tag in order of preference and then its
+// primary language, so that "de-CH" matches "de".
+func Negotiate(acceptLanguage string) string {
+	type choice struct {
+		tag string
+		q   float64
+	}
+	var choices []choice
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if tag != "" && q > 0 {
+			choices = append(choices, choice{strings.ToLower(tag), q})
+		}
+	}
+	sort.SliceStable(choices, func(i, j int) bool { return choices[i].q > choices[j].q })
+
+	for _, c := range choices {
+		if _, ok := catalogs[c.tag]; ok {
+			return c.tag
+		}
+		primary, _, _ := strings.Cut(c.tag, "-")
+		if _, ok := catalogs[primary]; ok {
+			return primary
+		}
+	}
+	return Fallback
+}
@@ -0,0 +1,47 @@
+package i18n
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestCatalogsAreComplete(t *testing.T) {
+	want := Codes(Fallback)
+	for _, lang := range Languages() {
+		if got := Codes(lang); !slices.Equal(got, want) {
+			t.Errorf("catalog %s has codes %v, want %v", lang, got, want)
+		}
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"", "en"},
+		{"fr", "fr"},
+		{"de-CH, es;q=0.5", "es"},
+		{"es;q=0.2, fr;q=0.8", "fr"},
+		{"fr;q=0, es", "es"},
+		{"ES-419", "es"},
+		{"*", "en"},
+	}
+	for _, tt := range tests {
+		if got := Negotiate(tt.header); got != tt.want {
+			t.Errorf("Negotiate(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestTranslate(t *testing.T) {
+	if got, want := Translate("es", "between", 1, 100), "debe estar entre 1 y 100"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got, want := Translate("xx", "required"), "must be provided"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got, want := Translate("fr", "no_such_code"), "no_such_code"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
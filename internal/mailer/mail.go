@@ -1,17 +1,81 @@
 package mailer
 
-import "gopkg.in/gomail.v2"
+import (
+	"bytes"
+	"embed"
+	htmltemplate "html/template"
+	"strings"
+	"text/template"
 
-func NewDialer(host string, port int, username, password string) *gomail.Dialer {
-	dialer := gomail.NewDialer(host, port, username, password)
-	return dialer
+	"gopkg.in/gomail.v2"
+)
+
+//go:embed "templates"
+var templateFS embed.FS
+
+// Message is an email with a plain-text and an optional HTML body.
+type Message struct {
+	From      string            `json:"from"`
+	To        string            `json:"to"`
+	Subject   string            `json:"subject"`
+	PlainBody string            `json:"plain_body"`
+	HTMLBody  string            `json:"html_body,omitempty"`
+	Headers   map[string]string `json:"headers,omitempty"`
+}
+
+// Mailer delivers a single message.
+type Mailer interface {
+	Send(msg *Message) error
+}
+
+// Render executes the "subject", "plainBody" and "htmlBody" templates defined
+// in templates/<name> and returns a message without sender or recipient.
+func Render(name string, data any) (*Message, error) {
+	tmpl, err := template.New("email").ParseFS(templateFS, "templates/"+name)
+	if err != nil {
+		return nil, err
+	}
+	subject := new(bytes.Buffer)
+	err = tmpl.ExecuteTemplate(subject, "subject", data)
+	if err != nil {
+		return nil, err
+	}
+	plainBody := new(bytes.Buffer)
+	err = tmpl.ExecuteTemplate(plainBody, "plainBody", data)
+	if err != nil {
+		return nil, err
+	}
+
+	htmlTmpl, err := htmltemplate.New("email").ParseFS(templateFS, "templates/"+name)
+	if err != nil {
+		return nil, err
+	}
+	htmlBody := new(bytes.Buffer)
+	err = htmlTmpl.ExecuteTemplate(htmlBody, "htmlBody", data)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Message{
+		Subject:   strings.TrimSpace(subject.String()),
+		PlainBody: strings.TrimSpace(plainBody.String()) + "\n",
+		HTMLBody:  htmlBody.String(),
+	}, nil
 }
 
-func SendMail(dialer *gomail.Dialer, from, to, subject, body string) error {
+// build turns msg into a MIME message, as multipart/alternative with the
+// plain-text body first when it has an HTML body.
+func build(msg *Message) *gomail.Message {
 	m := gomail.NewMessage()
-	m.SetHeader("From", from)
-	m.SetHeader("To", to)
-	m.SetHeader("Subject", subject)
-	m.SetBody("text/plain", body)
-	return dialer.DialAndSend(m)
+	m.SetHeader("From", msg.From)
+	m.SetHeader("To", msg.To)
+	m.SetHeader("Subject", msg.Subject)
+	for name, value := range msg.Headers {
+		m.SetHeader(name, value)
+	}
+	m.SetBody("text/plain", msg.PlainBody)
+	if msg.HTMLBody != "" {
+		m.AddAlternative("text/html", msg.HTMLBody)
+	}
+	return m
 }
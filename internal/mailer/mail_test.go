@@ -0,0 +1,77 @@
+package mailer
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRender(t *testing.T) {
+	msg, err := Render("reset_password.tmpl", map[string]any{
+		"Username":  "<ann>",
+		"Token":     "ABC123",
+		"ExpiresAt": time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Subject != "Reset your password" {
+		t.Errorf("got subject %q", msg.Subject)
+	}
+	for _, want := range []string{"Hi <ann>,", `{"token": "ABC123"`, "May 1, 2024 12:30 UTC"} {
+		if !strings.Contains(msg.PlainBody, want) {
+			t.Errorf("plain body does not contain %q:\n%s", want, msg.PlainBody)
+		}
+	}
+	if strings.HasPrefix(msg.PlainBody, "\n") || !strings.HasSuffix(msg.PlainBody, ".\n") {
+		t.Errorf("plain body is not trimmed to end with one newline: %q", msg.PlainBody)
+	}
+	for _, want := range []string{"<!doctype html>", "Hi &lt;ann&gt;,", "ABC123", "May 1, 2024 12:30 UTC"} {
+		if !strings.Contains(msg.HTMLBody, want) {
+			t.Errorf("HTML body does not contain %q:\n%s", want, msg.HTMLBody)
+		}
+	}
+	if strings.Contains(msg.HTMLBody, "<ann>") {
+		t.Error("HTML body does not escape the username")
+	}
+
+	_, err = Render("missing.tmpl", nil)
+	if err == nil {
+		t.Error("rendering a missing template succeeded")
+	}
+}
+
+func TestBuild(t *testing.T) {
+	var raw bytes.Buffer
+	_, err := build(&Message{
+		From:      "from@example.com",
+		To:        "to@example.com",
+		Subject:   "Hello",
+		PlainBody: "plain text\n",
+		HTMLBody:  "<p>html</p>",
+		Headers:   map[string]string{"List-Unsubscribe": "<https://example.com/u>"},
+	}).WriteTo(&raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := raw.String()
+	for _, want := range []string{"Content-Type: multipart/alternative", "List-Unsubscribe: <https://example.com/u>", "Subject: Hello"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("message does not contain %q:\n%s", want, got)
+		}
+	}
+	plain, html := strings.Index(got, "Content-Type: text/plain"), strings.Index(got, "Content-Type: text/html")
+	if plain < 0 || html < 0 || plain > html {
+		t.Errorf("want the plain-text part before the HTML part:\n%s", got)
+	}
+
+	raw.Reset()
+	_, err = build(&Message{From: "from@example.com", To: "to@example.com", PlainBody: "plain text\n"}).WriteTo(&raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(raw.String(), "multipart") {
+		t.Errorf("a message without HTML body is multipart:\n%s", raw.String())
+	}
+}
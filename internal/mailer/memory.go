@@ -0,0 +1,140 @@
+package mailer
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// outboxRow mirrors a row of the email_outbox table.
+type outboxRow struct {
+	id            int64
+	msg           Message
+	status        string
+	attempts      int
+	lastError     string
+	nextAttemptAt time.Time
+	sentAt        *time.Time
+	createdAt     time.Time
+}
+
+// MemoryStore keeps the outbox in the process, so queued messages are lost
+// on restart. It is meant for tests.
+type MemoryStore struct {
+	mu     sync.Mutex
+	nextId int64
+	rows   []*outboxRow
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{}
+}
+
+func (s *MemoryStore) Insert(ctx context.Context, msg *Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextId++
+	now := time.Now()
+	s.rows = append(s.rows, &outboxRow{
+		id:            s.nextId,
+		msg:           *msg,
+		status:        "pending",
+		nextAttemptAt: now,
+		createdAt:     now,
+	})
+	return nil
+}
+
+func (s *MemoryStore) Claim(ctx context.Context, limit int, lease time.Duration) ([]QueuedMessage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	var due []*outboxRow
+	for _, row := range s.rows {
+		if row.status == "pending" && !row.nextAttemptAt.After(now) {
+			due = append(due, row)
+		}
+	}
+	sort.SliceStable(due, func(i, j int) bool { return due[i].nextAttemptAt.Before(due[j].nextAttemptAt) })
+
+	var messages []QueuedMessage
+	for _, row := range due[:min(limit, len(due))] {
+		row.attempts++
+		row.nextAttemptAt = now.Add(lease)
+		messages = append(messages, QueuedMessage{Id: row.id, Attempts: row.attempts, Message: row.msg})
+	}
+	return messages, nil
+}
+
+func (s *MemoryStore) MarkSent(ctx context.Context, id int64) error {
+	return s.update(id, func(row *outboxRow) {
+		now := time.Now()
+		row.status = "sent"
+		row.sentAt = &now
+		row.lastError = ""
+		row.msg.PlainBody = ""
+		row.msg.HTMLBody = ""
+	})
+}
+
+func (s *MemoryStore) MarkFailed(ctx context.Context, id int64, sendErr error, retryAt time.Time) error {
+	return s.update(id, func(row *outboxRow) {
+		row.lastError = sendErr.Error()
+		row.nextAttemptAt = retryAt
+	})
+}
+
+func (s *MemoryStore) MarkDead(ctx context.Context, id int64, sendErr error) error {
+	return s.update(id, func(row *outboxRow) {
+		row.status = "dead"
+		row.lastError = sendErr.Error()
+	})
+}
+
+func (s *MemoryStore) Pending(ctx context.Context) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pending := 0
+	for _, row := range s.rows {
+		if row.status == "pending" {
+			pending++
+		}
+	}
+	return pending, nil
+}
+
+func (s *MemoryStore) DeleteFinished(ctx context.Context, olderThan time.Duration, limit int) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cutoff := time.Now().Add(-olderThan)
+	var deleted int64
+	kept := s.rows[:0]
+	for _, row := range s.rows {
+		if row.status != "pending" && !row.createdAt.After(cutoff) && deleted < int64(limit) {
+			deleted++
+			continue
+		}
+		kept = append(kept, row)
+	}
+	s.rows = kept
+	return deleted, nil
+}
+
+// update applies fn to the row with the given id. Like an UPDATE matching no
+// rows, an unknown id is not an error.
+func (s *MemoryStore) update(id int64, fn func(*outboxRow)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, row := range s.rows {
+		if row.id == id {
+			fn(row)
+		}
+	}
+	return nil
+}
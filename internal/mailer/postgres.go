@@ -0,0 +1,132 @@
+package mailer
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+// PostgresStore keeps the outbox in the email_outbox table, so that queued
+// messages survive restarts and are shared by every instance of the api.
+type PostgresStore struct {
+	DB           *sql.DB
+	QueryTimeout time.Duration
+}
+
+func (s *PostgresStore) Insert(ctx context.Context, msg *Message) error {
+	query := `
+	INSERT INTO email_outbox (sender, recipient, subject, plain_body, html_body, headers)
+	VALUES ($1, $2, $3, $4, $5, $6)`
+	ctx, cancel := context.WithTimeout(ctx, s.QueryTimeout)
+	defer cancel()
+
+	headers, err := json.Marshal(msg.Headers)
+	if err != nil {
+		return err
+	}
+	args := []any{msg.From, msg.To, msg.Subject, msg.PlainBody, msg.HTMLBody, headers}
+	_, err = s.DB.ExecContext(ctx, query, args...)
+	return err
+}
+
+func (s *PostgresStore) Claim(ctx context.Context, limit int, lease time.Duration) ([]QueuedMessage, error) {
+	query := `
+	UPDATE email_outbox
+	SET attempts = attempts + 1, next_attempt_at = now() + $2 * interval '1 second'
+	WHERE id IN (
+		SELECT id FROM email_outbox
+		WHERE status = 'pending' AND next_attempt_at <= now()
+		ORDER BY next_attempt_at
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED
+	)
+	RETURNING id, attempts, sender, recipient, subject, plain_body, html_body, headers`
+	ctx, cancel := context.WithTimeout(ctx, s.QueryTimeout)
+	defer cancel()
+
+	rows, err := s.DB.QueryContext(ctx, query, limit, lease.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var messages []QueuedMessage
+	for rows.Next() {
+		var m QueuedMessage
+		var headers []byte
+		err := rows.Scan(&m.Id, &m.Attempts, &m.Message.From, &m.Message.To, &m.Message.Subject, &m.Message.PlainBody, &m.Message.HTMLBody, &headers)
+		if err != nil {
+			return nil, err
+		}
+		err = json.Unmarshal(headers, &m.Message.Headers)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, m)
+	}
+	return messages, rows.Err()
+}
+
+func (s *PostgresStore) MarkSent(ctx context.Context, id int64) error {
+	query := `
+	UPDATE email_outbox
+	SET status = 'sent', sent_at = now(), last_error = '', plain_body = '', html_body = ''
+	WHERE id = $1`
+	return s.exec(ctx, query, id)
+}
+
+func (s *PostgresStore) MarkFailed(ctx context.Context, id int64, sendErr error, retryAt time.Time) error {
+	query := `
+	UPDATE email_outbox
+	SET last_error = $2, next_attempt_at = $3
+	WHERE id = $1`
+	return s.exec(ctx, query, id, sendErr.Error(), retryAt)
+}
+
+func (s *PostgresStore) MarkDead(ctx context.Context, id int64, sendErr error) error {
+	query := `
+	UPDATE email_outbox
+	SET status = 'dead', last_error = $2
+	WHERE id = $1`
+	return s.exec(ctx, query, id, sendErr.Error())
+}
+
+func (s *PostgresStore) Pending(ctx context.Context) (int, error) {
+	query := `
+	SELECT count(*)
+	FROM email_outbox
+	WHERE status = 'pending'`
+	ctx, cancel := context.WithTimeout(ctx, s.QueryTimeout)
+	defer cancel()
+
+	var pending int
+	err := s.DB.QueryRowContext(ctx, query).Scan(&pending)
+	return pending, err
+}
+
+func (s *PostgresStore) DeleteFinished(ctx context.Context, olderThan time.Duration, limit int) (int64, error) {
+	query := `
+	DELETE FROM email_outbox
+	WHERE id IN (
+		SELECT id FROM email_outbox
+		WHERE status <> 'pending'
+		AND created_at <= now() - $1 * interval '1 second'
+		LIMIT $2
+	)`
+	ctx, cancel := context.WithTimeout(ctx, s.QueryTimeout)
+	defer cancel()
+
+	result, err := s.DB.ExecContext(ctx, query, olderThan.Seconds(), limit)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func (s *PostgresStore) exec(ctx context.Context, query string, args ...any) error {
+	ctx, cancel := context.WithTimeout(ctx, s.QueryTimeout)
+	defer cancel()
+	_, err := s.DB.ExecContext(ctx, query, args...)
+	return err
+}
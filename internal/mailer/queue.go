@@ -0,0 +1,188 @@
+package mailer
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Delivery outcomes reported to Queue.OnDelivery.
+const (
+	OutcomeSent  = "sent"
+	OutcomeRetry = "retry"
+	OutcomeDead  = "dead"
+)
+
+// QueuedMessage is a message claimed from the outbox for delivery.
+type QueuedMessage struct {
+	Id       int64
+	Attempts int
+	Message  Message
+}
+
+// Store keeps the outbox of messages waiting to be delivered.
+type Store interface {
+	Insert(ctx context.Context, msg *Message) error
+	// Claim returns up to limit pending messages that are due, counts an
+	// attempt for each and hides them from other workers for lease.
+	Claim(ctx context.Context, limit int, lease time.Duration) ([]QueuedMessage, error)
+	// MarkSent records a delivery and clears the bodies, since they may
+	// hold secrets such as password reset tokens.
+	MarkSent(ctx context.Context, id int64) error
+	MarkFailed(ctx context.Context, id int64, sendErr error, retryAt time.Time) error
+	MarkDead(ctx context.Context, id int64, sendErr error) error
+	Pending(ctx context.Context) (int, error)
+	DeleteFinished(ctx context.Context, olderThan time.Duration, limit int) (int64, error)
+}
+
+// Queue stores outgoing messages in an outbox and delivers them from a
+// background worker. Failed sends are retried with exponential backoff until
+// MaxAttempts is reached, after which the message is dead-lettered.
+type Queue struct {
+	Store        Store
+	Mailer       Mailer
+	Logger       *slog.Logger
+	MaxAttempts  int
+	BaseDelay    time.Duration
+	MaxDelay     time.Duration
+	PollInterval time.Duration
+	BatchSize    int
+	// Lease is how long a claimed message stays hidden from other workers.
+	// Messages claimed by a process that dies are retried once it expires.
+	Lease time.Duration
+	// OnDelivery, when set, is called with the outcome of every attempt.
+	OnDelivery func(outcome string)
+
+	wake     chan struct{}
+	quit     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+func NewQueue(store Store, mailer Mailer, logger *slog.Logger) *Queue {
+	return &Queue{
+		Store:        store,
+		Mailer:       mailer,
+		Logger:       logger,
+		MaxAttempts:  5,
+		BaseDelay:    30 * time.Second,
+		MaxDelay:     time.Hour,
+		PollInterval: 5 * time.Second,
+		BatchSize:    10,
+		Lease:        5 * time.Minute,
+		wake:         make(chan struct{}, 1),
+		quit:         make(chan struct{}),
+		done:         make(chan struct{}),
+	}
+}
+
+func (q *Queue) Enqueue(ctx context.Context, msg *Message) error {
+	err := q.Store.Insert(ctx, msg)
+	if err != nil {
+		return err
+	}
+
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Run delivers queued messages until Shutdown is called. It is meant to be
+// started in its own goroutine.
+func (q *Queue) Run() {
+	defer close(q.done)
+	ticker := time.NewTicker(q.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		q.deliverDue()
+		select {
+		case <-q.quit:
+			return
+		case <-ticker.C:
+		case <-q.wake:
+		}
+	}
+}
+
+// Shutdown stops polling and waits for the batch being sent to finish.
+func (q *Queue) Shutdown(ctx context.Context) error {
+	q.stopOnce.Do(func() { close(q.quit) })
+	select {
+	case <-q.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (q *Queue) deliverDue() {
+	for {
+		select {
+		case <-q.quit:
+			return
+		default:
+		}
+		messages, err := q.Store.Claim(context.Background(), q.BatchSize, q.Lease)
+		if err != nil {
+			q.Logger.Error(err.Error())
+			return
+		}
+		for _, m := range messages {
+			q.deliver(m)
+		}
+		if len(messages) < q.BatchSize {
+			return
+		}
+	}
+}
+
+func (q *Queue) deliver(m QueuedMessage) {
+	ctx := context.Background()
+	sendErr := q.Mailer.Send(&m.Message)
+	var outcome string
+	var err error
+	switch {
+	case sendErr == nil:
+		outcome = OutcomeSent
+		err = q.Store.MarkSent(ctx, m.Id)
+	case m.Attempts >= q.MaxAttempts:
+		outcome = OutcomeDead
+		q.Logger.Error("email dead-lettered", "id", m.Id, "to", m.Message.To, "attempts", m.Attempts, "error", sendErr)
+		err = q.Store.MarkDead(ctx, m.Id, sendErr)
+	default:
+		outcome = OutcomeRetry
+		q.Logger.Warn("email delivery failed", "id", m.Id, "to", m.Message.To, "attempts", m.Attempts, "error", sendErr)
+		err = q.Store.MarkFailed(ctx, m.Id, sendErr, time.Now().Add(q.backoff(m.Attempts)))
+	}
+	if q.OnDelivery != nil {
+		q.OnDelivery(outcome)
+	}
+	if err != nil {
+		q.Logger.Error(err.Error())
+	}
+}
+
+// backoff returns the delay before the next attempt after the given number
+// of failed attempts.
+func (q *Queue) backoff(attempts int) time.Duration {
+	delay := q.BaseDelay
+	for i := 1; i < attempts && delay < q.MaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, q.MaxDelay)
+}
+
+// Pending returns the number of messages waiting to be delivered.
+func (q *Queue) Pending(ctx context.Context) (int, error) {
+	return q.Store.Pending(ctx)
+}
+
+// DeleteFinished removes up to limit sent or dead-lettered messages created
+// more than olderThan ago and returns how many were deleted.
+func (q *Queue) DeleteFinished(ctx context.Context, olderThan time.Duration, limit int) (int64, error) {
+	return q.Store.DeleteFinished(ctx, olderThan, limit)
+}
@@ -0,0 +1,238 @@
+package mailer
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// scriptedMailer fails with the errors in fail, in order, and then succeeds.
+type scriptedMailer struct {
+	mu   sync.Mutex
+	fail []error
+	sent []Message
+}
+
+func (m *scriptedMailer) Send(msg *Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.fail) > 0 {
+		err := m.fail[0]
+		m.fail = m.fail[1:]
+		return err
+	}
+	m.sent = append(m.sent, *msg)
+	return nil
+}
+
+func newTestQueue(mailer Mailer) (*Queue, *MemoryStore, *[]string) {
+	store := NewMemoryStore()
+	q := NewQueue(store, mailer, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	q.BaseDelay = time.Hour
+	q.MaxDelay = 4 * time.Hour
+	var outcomes []string
+	q.OnDelivery = func(outcome string) { outcomes = append(outcomes, outcome) }
+	return q, store, &outcomes
+}
+
+// makeDue moves the next attempt of every message to the past, as if their
+// backoff or lease had run out.
+func makeDue(s *MemoryStore) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, row := range s.rows {
+		row.nextAttemptAt = time.Now().Add(-time.Second)
+	}
+}
+
+var testMessage = &Message{
+	From:      "from@example.com",
+	To:        "to@example.com",
+	Subject:   "Hello",
+	PlainBody: "token: secret\n",
+	HTMLBody:  "<p>token: secret</p>",
+	Headers:   map[string]string{"X-Test": "1"},
+}
+
+func TestBackoff(t *testing.T) {
+	q := NewQueue(nil, nil, nil)
+	q.BaseDelay = 30 * time.Second
+	q.MaxDelay = time.Hour
+	for attempts, want := range map[int]time.Duration{
+		1: 30 * time.Second,
+		2: time.Minute,
+		3: 2 * time.Minute,
+		7: 32 * time.Minute,
+		8: time.Hour,
+		9: time.Hour,
+	} {
+		if got := q.backoff(attempts); got != want {
+			t.Errorf("backoff(%d) = %s, want %s", attempts, got, want)
+		}
+	}
+}
+
+func TestQueueRetry(t *testing.T) {
+	mailer := &scriptedMailer{fail: []error{errors.New("refused"), errors.New("timeout")}}
+	q, store, outcomes := newTestQueue(mailer)
+	if err := q.Enqueue(context.Background(), testMessage); err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	q.deliverDue()
+	row := store.rows[0]
+	if row.status != "pending" || row.attempts != 1 || row.lastError != "refused" {
+		t.Fatalf("after a failure: got %+v", row)
+	}
+	if retryAt := row.nextAttemptAt.Sub(start); retryAt < q.BaseDelay || retryAt > q.BaseDelay+time.Minute {
+		t.Errorf("first retry in %s, want %s", retryAt, q.BaseDelay)
+	}
+	// Not due again until the backoff has passed.
+	q.deliverDue()
+	if row.attempts != 1 {
+		t.Errorf("retried before the backoff: %d attempts", row.attempts)
+	}
+
+	makeDue(store)
+	start = time.Now()
+	q.deliverDue()
+	if retryAt := row.nextAttemptAt.Sub(start); retryAt < 2*q.BaseDelay || retryAt > 2*q.BaseDelay+time.Minute {
+		t.Errorf("second retry in %s, want %s", retryAt, 2*q.BaseDelay)
+	}
+
+	makeDue(store)
+	q.deliverDue()
+	if want := []string{OutcomeRetry, OutcomeRetry, OutcomeSent}; !reflect.DeepEqual(*outcomes, want) {
+		t.Errorf("got outcomes %v, want %v", *outcomes, want)
+	}
+	if len(mailer.sent) != 1 || !reflect.DeepEqual(mailer.sent[0], *testMessage) {
+		t.Errorf("sent %+v, want %+v", mailer.sent, *testMessage)
+	}
+	if row.status != "sent" || row.sentAt == nil || row.lastError != "" || row.attempts != 3 {
+		t.Errorf("after delivery: got %+v", row)
+	}
+	if row.msg.PlainBody != "" || row.msg.HTMLBody != "" {
+		t.Errorf("bodies were kept after delivery: %q, %q", row.msg.PlainBody, row.msg.HTMLBody)
+	}
+	if pending, _ := q.Pending(context.Background()); pending != 0 {
+		t.Errorf("%d pending, want 0", pending)
+	}
+}
+
+func TestQueueDeadLetter(t *testing.T) {
+	fail := make([]error, 10)
+	for i := range fail {
+		fail[i] = errors.New("mailbox unavailable")
+	}
+	q, store, outcomes := newTestQueue(&scriptedMailer{fail: fail})
+	q.MaxAttempts = 3
+	if err := q.Enqueue(context.Background(), testMessage); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 5; i++ {
+		q.deliverDue()
+		makeDue(store)
+	}
+	if want := []string{OutcomeRetry, OutcomeRetry, OutcomeDead}; !reflect.DeepEqual(*outcomes, want) {
+		t.Errorf("got outcomes %v, want %v", *outcomes, want)
+	}
+	row := store.rows[0]
+	if row.status != "dead" || row.attempts != 3 || row.lastError != "mailbox unavailable" {
+		t.Errorf("got %+v, want a dead-lettered message", row)
+	}
+	if pending, _ := q.Pending(context.Background()); pending != 0 {
+		t.Errorf("%d pending, want 0", pending)
+	}
+
+	// Finished messages are kept for the retention period.
+	ctx := context.Background()
+	if n, _ := q.DeleteFinished(ctx, time.Hour, 10); n != 0 {
+		t.Errorf("deleted %d messages within the retention period", n)
+	}
+	if n, _ := q.DeleteFinished(ctx, 0, 10); n != 1 {
+		t.Errorf("deleted %d messages after the retention period, want 1", n)
+	}
+}
+
+func TestQueueLease(t *testing.T) {
+	mailer := &scriptedMailer{}
+	q, store, _ := newTestQueue(mailer)
+	ctx := context.Background()
+	if err := q.Enqueue(ctx, testMessage); err != nil {
+		t.Fatal(err)
+	}
+
+	// A worker claims the message and dies before sending it.
+	claimed, err := store.Claim(ctx, 10, q.Lease)
+	if err != nil || len(claimed) != 1 {
+		t.Fatalf("claimed %v, err %v", claimed, err)
+	}
+	q.deliverDue()
+	if len(mailer.sent) != 0 {
+		t.Fatal("a leased message was sent again")
+	}
+
+	makeDue(store)
+	q.deliverDue()
+	if len(mailer.sent) != 1 {
+		t.Fatal("the message was not sent once the lease expired")
+	}
+	if row := store.rows[0]; row.status != "sent" || row.attempts != 2 {
+		t.Errorf("got %+v, want sent on the second attempt", row)
+	}
+}
+
+// blockingMailer holds every send until release is closed.
+type blockingMailer struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (m *blockingMailer) Send(msg *Message) error {
+	m.started <- struct{}{}
+	<-m.release
+	return nil
+}
+
+func TestQueueShutdownDrains(t *testing.T) {
+	mailer := &blockingMailer{started: make(chan struct{}, 1), release: make(chan struct{})}
+	q, store, outcomes := newTestQueue(mailer)
+	go q.Run()
+
+	if err := q.Enqueue(context.Background(), testMessage); err != nil {
+		t.Fatal(err)
+	}
+	<-mailer.started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := q.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("shutdown during a send: got error %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	close(mailer.release)
+	if err := q.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{OutcomeSent}; !reflect.DeepEqual(*outcomes, want) {
+		t.Errorf("got outcomes %v, want %v", *outcomes, want)
+	}
+	if row := store.rows[0]; row.status != "sent" {
+		t.Errorf("got status %q after draining, want sent", row.status)
+	}
+
+	// Nothing is delivered once the queue has stopped.
+	if err := q.Enqueue(context.Background(), testMessage); err != nil {
+		t.Fatal(err)
+	}
+	if pending, _ := q.Pending(context.Background()); pending != 1 {
+		t.Errorf("%d pending after shutdown, want 1", pending)
+	}
+}
@@ -0,0 +1,93 @@
+package mailer
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/gomail.v2"
+)
+
+// SMTPMailer sends messages through an SMTP server.
+type SMTPMailer struct {
+	dialer *gomail.Dialer
+}
+
+func NewSMTPMailer(host string, port int, username, password string) *SMTPMailer {
+	return &SMTPMailer{dialer: gomail.NewDialer(host, port, username, password)}
+}
+
+func (m *SMTPMailer) Send(msg *Message) error {
+	return m.dialer.DialAndSend(build(msg))
+}
+
+// FileMailer appends messages to an mbox file so they can be read with any
+// mail client during development.
+type FileMailer struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewFileMailer(path string) *FileMailer {
+	return &FileMailer{path: path}
+}
+
+func (m *FileMailer) Send(msg *Message) error {
+	var raw bytes.Buffer
+	_, err := build(msg).WriteTo(&raw)
+	if err != nil {
+		return err
+	}
+
+	var entry bytes.Buffer
+	fmt.Fprintf(&entry, "From %s %s\n", msg.From, time.Now().UTC().Format(time.ANSIC))
+	scanner := bufio.NewScanner(&raw)
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if strings.HasPrefix(strings.TrimLeft(line, ">"), "From ") {
+			line = ">" + line
+		}
+		entry.WriteString(line + "\n")
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	entry.WriteString("\n")
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	f, err := os.OpenFile(m.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(entry.Bytes())
+	if err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Recorder keeps sent messages in memory for tests.
+type Recorder struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+func (m *Recorder) Send(msg *Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages = append(m.messages, *msg)
+	return nil
+}
+
+// Messages returns a copy of every message sent so far.
+func (m *Recorder) Messages() []Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Message(nil), m.messages...)
+}
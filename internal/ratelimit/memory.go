@@ -0,0 +1,63 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// MemoryStore keeps buckets in the process, so limits are not shared between
+// instances.
+type MemoryStore struct {
+	mu         sync.Mutex
+	buckets    map[string]*bucket
+	staleAfter time.Duration
+	lastSweep  time.Time
+}
+
+// NewMemoryStore returns a store that forgets buckets unused for staleAfter,
+// which should be at least the longest period of the limits it is used with.
+func NewMemoryStore(staleAfter time.Duration) *MemoryStore {
+	return &MemoryStore{buckets: make(map[string]*bucket), staleAfter: staleAfter, lastSweep: time.Now()}
+}
+
+func (s *MemoryStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.sweep(now)
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst), updated: now}
+		s.buckets[key] = b
+	}
+	b.tokens = min(float64(limit.Burst), b.tokens+now.Sub(b.updated).Seconds()*limit.Rate)
+	b.updated = now
+
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+	return result(limit, b.tokens, allowed), nil
+}
+
+// sweep drops buckets that have not been used for staleAfter, about once a
+// minute. Such buckets are full again for any limit refilling within that time.
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < time.Minute {
+		return
+	}
+	s.lastSweep = now
+	for key, b := range s.buckets {
+		if now.Sub(b.updated) > s.staleAfter {
+			delete(s.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryStoreSweep(t *testing.T) {
+	ctx := context.Background()
+	daily := Limit{Rate: 1.0 / (24 * 60 * 60), Burst: 1}
+	s := NewMemoryStore(daily.Period())
+
+	if res, _ := s.Take(ctx, "ann", daily); !res.Allowed {
+		t.Fatal("first request was not allowed")
+	}
+	if res, _ := s.Take(ctx, "bob", daily); !res.Allowed {
+		t.Fatal("first request was not allowed")
+	}
+
+	// Two hours on, the sweep must not forget buckets that are still
+	// refilling, which would lift the limit early.
+	s.lastSweep = time.Now().Add(-time.Hour)
+	s.buckets["ann"].updated = time.Now().Add(-2 * time.Hour)
+	if res, _ := s.Take(ctx, "ann", daily); res.Allowed {
+		t.Error("request allowed two hours into a daily limit")
+	}
+
+	s.lastSweep = time.Now().Add(-time.Hour)
+	s.buckets["bob"].updated = time.Now().Add(-25 * time.Hour)
+	s.Take(ctx, "ann", daily)
+	if _, ok := s.buckets["bob"]; ok {
+		t.Error("a bucket unused for longer than the period was kept")
+	}
+}
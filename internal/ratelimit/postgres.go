@@ -0,0 +1,54 @@
+package ratelimit
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// PostgresStore keeps buckets in the rate_limits table so that every instance
+// of the api shares them.
+type PostgresStore struct {
+	DB           *sql.DB
+	QueryTimeout time.Duration
+}
+
+func (s *PostgresStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	// The refilled bucket is computed twice so that the whole update happens
+	// in one statement and concurrent requests for a key are serialized.
+	query := `
+		INSERT INTO rate_limits AS rl (key, tokens, allowed, updated_at)
+		VALUES ($1, $3::float8 - 1, true, NOW())
+		ON CONFLICT (key) DO UPDATE SET
+			tokens = CASE
+				WHEN LEAST($3, rl.tokens + EXTRACT(EPOCH FROM NOW() - rl.updated_at) * $2::float8) >= 1
+				THEN LEAST($3, rl.tokens + EXTRACT(EPOCH FROM NOW() - rl.updated_at) * $2::float8) - 1
+				ELSE LEAST($3, rl.tokens + EXTRACT(EPOCH FROM NOW() - rl.updated_at) * $2::float8)
+			END,
+			allowed = LEAST($3, rl.tokens + EXTRACT(EPOCH FROM NOW() - rl.updated_at) * $2::float8) >= 1,
+			updated_at = NOW()
+		RETURNING tokens, allowed`
+	ctx, cancel := context.WithTimeout(ctx, s.QueryTimeout)
+	defer cancel()
+
+	var tokens float64
+	var allowed bool
+	err := s.DB.QueryRowContext(ctx, query, key, limit.Rate, limit.Burst).Scan(&tokens, &allowed)
+	if err != nil {
+		return Result{}, err
+	}
+	return result(limit, tokens, allowed), nil
+}
+
+// DeleteStale removes buckets that have not been used for olderThan.
+func (s *PostgresStore) DeleteStale(ctx context.Context, olderThan time.Duration) (int64, error) {
+	query := `DELETE FROM rate_limits WHERE updated_at < NOW() - $1 * INTERVAL '1 second'`
+	ctx, cancel := context.WithTimeout(ctx, s.QueryTimeout)
+	defer cancel()
+
+	res, err := s.DB.ExecContext(ctx, query, olderThan.Seconds())
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,53 @@
+package validator
+
+import (
+	"cmp"
+	"net/url"
+	"regexp"
+	"slices"
+	"unicode/utf8"
+)
+
+func ValidEmail(email string) bool {
+	return EmailPatter.MatchString(email)
+}
+
+func Unique(value ...string) bool {
+	uniqueValues := make(map[string]bool)
+	for _, v := range value {
+		if _, ok := uniqueValues[v]; !ok {
+			uniqueValues[v] = true
+		}
+	}
+	return len(uniqueValues) == len(value)
+}
+
+// MinRunes reports whether s has at least n characters.
+func MinRunes(s string, n int) bool {
+	return utf8.RuneCountInString(s) >= n
+}
+
+// MaxRunes reports whether s has at most n characters.
+func MaxRunes(s string, n int) bool {
+	return utf8.RuneCountInString(s) <= n
+}
+
+func Matches(s string, rx *regexp.Regexp) bool {
+	return rx.MatchString(s)
+}
+
+// In reports whether value is one of permitted.
+func In[T comparable](value T, permitted ...T) bool {
+	return slices.Contains(permitted, value)
+}
+
+// URL reports whether s is an absolute http or https URL.
+func URL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// Between reports whether value lies within [min, max].
+func Between[T cmp.Ordered](value, min, max T) bool {
+	return value >= min && value <= max
+}
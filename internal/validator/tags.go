@@ -0,0 +1,154 @@
+package validator
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Struct checks the fields of the struct pointed to by s against the rules in
+// their validate tags, recursing into nested structs and slices of structs.
+// Fields are reported under their JSON names. The rules are:
+//
+//	required       not the zero value, or not empty for strings and slices
+//	email          a valid email address
+//	url            an absolute http or https URL
+//	minrunes=N     at least N characters
+//	maxrunes=N     at most N characters
+//	between=A|B    a number within [A, B]
+//	in=A|B|C       one of the listed values
+//	unique         a slice of strings without duplicates
+//
+// Only required applies to empty values, so optional fields can combine
+// other rules without it. Struct panics on malformed tags.
+func (v *Validator) Struct(s any) {
+	rv := reflect.Indirect(reflect.ValueOf(s))
+	if rv.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("validator: Struct called with %T", s))
+	}
+	if !rv.CanAddr() {
+		addressable := reflect.New(rv.Type()).Elem()
+		addressable.Set(rv)
+		rv = addressable
+	}
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		key := fieldKey(field)
+		value := rv.Field(i)
+		if tag, ok := field.Tag.Lookup("validate"); ok {
+			for _, rule := range strings.Split(tag, ",") {
+				v.checkRule(key, value, rule)
+			}
+		}
+
+		switch {
+		case value.Kind() == reflect.Struct:
+			v.Scope(key).Struct(value.Addr().Interface())
+		case value.Kind() == reflect.Pointer && !value.IsNil() && value.Elem().Kind() == reflect.Struct:
+			v.Scope(key).Struct(value.Interface())
+		case value.Kind() == reflect.Slice && value.Type().Elem().Kind() == reflect.Struct:
+			for j := 0; j < value.Len(); j++ {
+				v.Index(key, j).Struct(value.Index(j).Addr().Interface())
+			}
+		}
+	}
+}
+
+func fieldKey(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "" || name == "-" {
+		return field.Name
+	}
+	return name
+}
+
+func (v *Validator) checkRule(key string, value reflect.Value, rule string) {
+	name, arg, _ := strings.Cut(strings.TrimSpace(rule), "=")
+	if value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			v.Check(name != "required", key, Required)
+			return
+		}
+		value = value.Elem()
+	}
+	if name == "required" {
+		v.Check(!isEmpty(value), key, Required)
+		return
+	}
+	if isEmpty(value) {
+		return
+	}
+
+	switch name {
+	case "email":
+		v.Check(ValidEmail(value.String()), key, InvalidEmail)
+	case "url":
+		v.Check(URL(value.String()), key, InvalidURL)
+	case "minrunes":
+		n := atoi(rule, arg)
+		v.Check(MinRunes(value.String(), n), key, TooShort, n)
+	case "maxrunes":
+		n := atoi(rule, arg)
+		v.Check(MaxRunes(value.String(), n), key, TooLong, n)
+	case "between":
+		lo, hi, ok := strings.Cut(arg, "|")
+		if !ok {
+			panic(fmt.Sprintf("validator: invalid rule %q", rule))
+		}
+		min, max := parseFloat(rule, lo), parseFloat(rule, hi)
+		v.Check(Between(number(rule, value), min, max), key, OutOfRange, lo, hi)
+	case "in":
+		permitted := strings.Split(arg, "|")
+		v.Check(In(fmt.Sprint(value.Interface()), permitted...), key, NotPermitted, strings.Join(permitted, ", "))
+	case "unique":
+		values, ok := value.Interface().([]string)
+		if !ok {
+			panic(fmt.Sprintf("validator: rule %q needs a []string", rule))
+		}
+		v.Check(Unique(values...), key, NotUnique)
+	default:
+		panic(fmt.Sprintf("validator: unknown rule %q", rule))
+	}
+}
+
+func isEmpty(value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		return value.Len() == 0
+	default:
+		return value.IsZero()
+	}
+}
+
+func number(rule string, value reflect.Value) float64 {
+	switch {
+	case value.CanInt():
+		return float64(value.Int())
+	case value.CanUint():
+		return float64(value.Uint())
+	case value.CanFloat():
+		return value.Float()
+	}
+	panic(fmt.Sprintf("validator: rule %q needs a number, got %s", rule, value.Kind()))
+}
+
+func atoi(rule, s string) int {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		panic(fmt.Sprintf("validator: invalid rule %q", rule))
+	}
+	return n
+}
+
+func parseFloat(rule, s string) float64 {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		panic(fmt.Sprintf("validator: invalid rule %q", rule))
+	}
+	return f
+}
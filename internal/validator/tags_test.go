@@ -0,0 +1,179 @@
+package validator
+
+import (
+	"reflect"
+	"testing"
+)
+
+type tagged struct {
+	Title string `json:"title" validate:"required,maxrunes=5"`
+}
+
+type author struct {
+	Email   string `json:"email" validate:"required,email"`
+	Website string `json:"website" validate:"url"`
+}
+
+type form struct {
+	Name     string   `json:"name" validate:"required,minrunes=2,maxrunes=4"`
+	Email    string   `json:"email" validate:"email"`
+	Site     string   `json:"site,omitempty" validate:"url"`
+	Age      int      `json:"age" validate:"between=18|99"`
+	Ratio    float64  `json:"ratio" validate:"between=0|1"`
+	Kind     string   `json:"kind" validate:"in=idea|project"`
+	Labels   []string `json:"labels" validate:"unique"`
+	Count    *int     `json:"count" validate:"required,between=1|3"`
+	Optional *string  `json:"optional" validate:"minrunes=3"`
+	NoJSON   string   `validate:"required"`
+	Author   author   `json:"author"`
+	Editor   *author  `json:"editor"`
+	Tags     []tagged `json:"tags" validate:"required"`
+	internal string   `validate:"required"`
+}
+
+func intPtr(n int) *int       { return &n }
+func strPtr(s string) *string { return &s }
+
+func codes(errs []Error) []string {
+	var c []string
+	for _, e := range errs {
+		c = append(c, e.Code)
+	}
+	return c
+}
+
+func valid() form {
+	return form{
+		Name:   "ann",
+		Count:  intPtr(2),
+		NoJSON: "set",
+		Author: author{Email: "ann@example.com"},
+		Tags:   []tagged{{Title: "go"}},
+	}
+}
+
+func TestStruct(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(f *form)
+		want   map[string][]string
+	}{
+		{"valid", func(f *form) {}, map[string][]string{}},
+		{"all optional rules pass", func(f *form) {
+			f.Email = "ann@example.com"
+			f.Site = "https://example.com/a"
+			f.Age = 18
+			f.Ratio = 0.5
+			f.Kind = "project"
+			f.Labels = []string{"a", "b"}
+			f.Optional = strPtr("abc")
+			f.Editor = &author{Email: "ed@example.com", Website: "http://example.com"}
+		}, map[string][]string{}},
+		{"required", func(f *form) {
+			f.Name = ""
+			f.Count = nil
+			f.NoJSON = ""
+			f.Tags = nil
+		}, map[string][]string{"name": {Required}, "count": {Required}, "NoJSON": {Required}, "tags": {Required}}},
+		{"runes", func(f *form) { f.Name = "ännaa" }, map[string][]string{"name": {TooLong}}},
+		{"min runes", func(f *form) { f.Name = "ä" }, map[string][]string{"name": {TooShort}}},
+		{"email", func(f *form) { f.Email = "ann" }, map[string][]string{"email": {InvalidEmail}}},
+		{"url", func(f *form) { f.Site = "ftp://example.com" }, map[string][]string{"site": {InvalidURL}}},
+		{"relative url", func(f *form) { f.Site = "/a" }, map[string][]string{"site": {InvalidURL}}},
+		{"between int", func(f *form) { f.Age = 100 }, map[string][]string{"age": {OutOfRange}}},
+		{"between float", func(f *form) { f.Ratio = 1.5 }, map[string][]string{"ratio": {OutOfRange}}},
+		{"between pointer", func(f *form) { f.Count = intPtr(4) }, map[string][]string{"count": {OutOfRange}}},
+		{"in", func(f *form) { f.Kind = "other" }, map[string][]string{"kind": {NotPermitted}}},
+		{"unique", func(f *form) { f.Labels = []string{"a", "a"} }, map[string][]string{"labels": {NotUnique}}},
+		{"optional pointer", func(f *form) { f.Optional = strPtr("ab") }, map[string][]string{"optional": {TooShort}}},
+		{"nested struct", func(f *form) {
+			f.Author = author{Email: "bad", Website: "nope"}
+		}, map[string][]string{"author.email": {InvalidEmail}, "author.website": {InvalidURL}}},
+		{"nested pointer", func(f *form) {
+			f.Editor = &author{}
+		}, map[string][]string{"editor.email": {Required}}},
+		{"slice of structs", func(f *form) {
+			f.Tags = []tagged{{Title: "go"}, {Title: ""}, {Title: "toolong"}}
+		}, map[string][]string{"tags[1].title": {Required}, "tags[2].title": {TooLong}}},
+		{"unexported fields are skipped", func(f *form) { f.internal = "" }, map[string][]string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := valid()
+			tt.modify(&f)
+			v := New()
+			v.Struct(&f)
+
+			got := make(map[string][]string)
+			for key, errs := range v.Errors {
+				got[key] = codes(errs)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStructArgs(t *testing.T) {
+	f := valid()
+	f.Age = 10
+	f.Kind = "x"
+	f.Name = "abcdef"
+	v := New()
+	v.Struct(f)
+
+	want := map[string][]Error{
+		"age":  {{Code: OutOfRange, Args: []any{"18", "99"}}},
+		"kind": {{Code: NotPermitted, Args: []any{"idea, project"}}},
+		"name": {{Code: TooLong, Args: []any{4}}},
+	}
+	if !reflect.DeepEqual(v.Errors, want) {
+		t.Errorf("got %v, want %v", v.Errors, want)
+	}
+}
+
+func TestStructScope(t *testing.T) {
+	v := New()
+	v.Scope("input").Index("items", 0).Struct(&tagged{})
+	if _, ok := v.Errors["input.items[0].title"]; !ok {
+		t.Errorf("got keys %v, want input.items[0].title", v.Errors)
+	}
+}
+
+func TestStructPanics(t *testing.T) {
+	tests := []struct {
+		name  string
+		value any
+	}{
+		{"not a struct", 3},
+		{"unknown rule", &struct {
+			A string `validate:"nope"`
+		}{A: "x"}},
+		{"bad number", &struct {
+			A string `validate:"maxrunes=x"`
+		}{A: "x"}},
+		{"between without bounds", &struct {
+			A int `validate:"between=1"`
+		}{A: 1}},
+		{"bad bound", &struct {
+			A int `validate:"between=1|x"`
+		}{A: 1}},
+		{"between on a string", &struct {
+			A string `validate:"between=1|2"`
+		}{A: "x"}},
+		{"unique on ints", &struct {
+			A []int `validate:"unique"`
+		}{A: []int{1}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("Struct did not panic")
+				}
+			}()
+			New().Struct(tt.value)
+		})
+	}
+}
@@ -1,45 +1,93 @@
 package validator
 
-import "regexp"
+import (
+	"regexp"
+	"strconv"
+)
 
+// Codes of the errors added by the rules in this package. Callers may use
+// their own codes as well; they are translated by the i18n catalogs.
+const (
+	Required     = "required"
+	InvalidEmail = "email"
+	InvalidURL   = "url"
+	TooShort     = "min_runes"
+	TooLong      = "max_runes"
+	TooManyBytes = "max_bytes"
+	OutOfRange   = "between"
+	NotPermitted = "in"
+	NotUnique    = "unique"
+	NotAnInteger = "integer"
+	NotABoolean  = "boolean"
+)
+
+// Codes of errors that callers check for themselves.
+const (
+	InvalidCursor = "invalid_cursor"
+	// UnknownEvent takes the unknown notification events as its argument.
+	UnknownEvent = "unknown_event"
+)
+
+// Error is a message code and the arguments filled into its translation.
+type Error struct {
+	Code string
+	Args []any
+}
+
+// Validator collects errors keyed by field. Keys of nested values are paths
+// such as tags[2].title.
 type Validator struct {
-	Errors map[string]any
+	Errors map[string][]Error
+	prefix string
 }
 
 var EmailPatter = regexp.MustCompile("^[a-zA-Z0-9.!#$%&'*+/=?^_`{|}~-]+@[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(?:\\.[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*$")
 
 func New() *Validator {
 	return &Validator{
-		Errors: make(map[string]any),
+		Errors: make(map[string][]Error),
 	}
 }
 
-func (v *Validator) AddError(key, message string) {
-	if _, found := v.Errors[key]; !found {
-		v.Errors[key] = message
-	}
+// Scope returns a validator that adds its errors to v under key, so that a
+// field title checked through Scope("author") is reported as author.title.
+func (v *Validator) Scope(key string) *Validator {
+	return &Validator{Errors: v.Errors, prefix: v.key(key)}
 }
 
-func (v *Validator) Check(ok bool, key, message string) {
-	if !ok {
-		v.AddError(key, message)
-	}
+// Index is Scope for the element i of the list key, e.g. tags[2].
+func (v *Validator) Index(key string, i int) *Validator {
+	return &Validator{Errors: v.Errors, prefix: v.key(key) + "[" + strconv.Itoa(i) + "]"}
 }
 
-func (v *Validator) Valid() bool {
-	return len(v.Errors) == 0
+func (v *Validator) key(key string) string {
+	switch {
+	case v.prefix == "":
+		return key
+	case key == "":
+		return v.prefix
+	default:
+		return v.prefix + "." + key
+	}
 }
 
-func ValidEmail(email string) bool {
-	return EmailPatter.MatchString(email)
+// AddError records the error code with args for key, once.
+func (v *Validator) AddError(key, code string, args ...any) {
+	key = v.key(key)
+	for _, e := range v.Errors[key] {
+		if e.Code == code {
+			return
+		}
+	}
+	v.Errors[key] = append(v.Errors[key], Error{Code: code, Args: args})
 }
 
-func Unique(value ...string) bool {
-	uniqueValues := make(map[string]bool)
-	for _, v := range value {
-		if _, ok := uniqueValues[v]; !ok {
-			uniqueValues[v] = true
-		}
+func (v *Validator) Check(ok bool, key, code string, args ...any) {
+	if !ok {
+		v.AddError(key, code, args...)
 	}
-	return len(uniqueValues) == len(value)
+}
+
+func (v *Validator) Valid() bool {
+	return len(v.Errors) == 0
 }
@@ -0,0 +1,5 @@
+DELETE FROM users WHERE is_ghost;
+DROP INDEX IF EXISTS users_is_ghost_idx;
+ALTER TABLE users DROP COLUMN IF EXISTS is_ghost;
+ALTER TABLE users DROP COLUMN IF EXISTS reassign_ideas;
+ALTER TABLE users DROP COLUMN IF EXISTS delete_after;
\ No newline at end of file
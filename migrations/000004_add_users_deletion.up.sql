@@ -0,0 +1,22 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS delete_after timestamptz;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS reassign_ideas boolean NOT NULL DEFAULT false;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS is_ghost boolean NOT NULL DEFAULT false;
+
+-- The ghost account takes over reassigned ideas. A user who registered as
+-- "ghost" first must not be mistaken for it.
+DO $$
+BEGIN
+    IF EXISTS (
+        SELECT 1 FROM users
+        WHERE username = 'ghost'
+        AND (email <> 'ghost@localhost' OR hash_password <> '*')
+    ) THEN
+        RAISE EXCEPTION 'a user registered as "ghost" exists; rename them before migrating';
+    END IF;
+END $$;
+
+INSERT INTO users (username, email, hash_password, is_ghost)
+VALUES ('ghost', 'ghost@localhost', '*', true)
+ON CONFLICT DO NOTHING;
+
+CREATE UNIQUE INDEX IF NOT EXISTS users_is_ghost_idx ON users (is_ghost) WHERE is_ghost;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS ideas_created_at_id_idx;
+DROP TABLE IF EXISTS follows;
\ No newline at end of file
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS follows(
+    follower_id int NOT NULL REFERENCES users ON DELETE CASCADE,
+    user_id int REFERENCES users ON DELETE CASCADE,
+    tag_id int REFERENCES tags ON DELETE CASCADE,
+    created_at timestamptz NOT NULL DEFAULT NOW(),
+    CHECK (num_nonnulls(user_id, tag_id) = 1)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS follows_follower_id_user_id_idx ON follows (follower_id, user_id) WHERE user_id IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS follows_follower_id_tag_id_idx ON follows (follower_id, tag_id) WHERE tag_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS ideas_created_at_id_idx ON ideas (created_at DESC, id DESC);
@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS notifications(
+    id serial PRIMARY KEY,
+    user_id int NOT NULL REFERENCES users ON DELETE CASCADE,
+    actor_id int REFERENCES users ON DELETE SET NULL,
+    idea_id int REFERENCES ideas ON DELETE CASCADE,
+    event text NOT NULL,
+    read_at timestamptz,
+    created_at timestamptz NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS notifications_user_id_created_at_idx ON notifications (user_id, created_at DESC, id DESC);
+
+CREATE TABLE IF NOT EXISTS notification_preferences(
+    user_id int NOT NULL REFERENCES users ON DELETE CASCADE,
+    event text NOT NULL,
+    enabled boolean NOT NULL,
+    PRIMARY KEY (user_id, event)
+);
@@ -0,0 +1,2 @@
+ALTER TABLE users DROP COLUMN IF EXISTS digest_sent_at;
+ALTER TABLE users DROP COLUMN IF EXISTS digest_frequency;
\ No newline at end of file
@@ -0,0 +1,3 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS digest_frequency text NOT NULL DEFAULT 'never'
+    CHECK (digest_frequency IN ('never', 'daily', 'weekly'));
+ALTER TABLE users ADD COLUMN IF NOT EXISTS digest_sent_at timestamptz;
@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS email_outbox(
+    id bigserial PRIMARY KEY,
+    sender text NOT NULL,
+    recipient text NOT NULL,
+    subject text NOT NULL,
+    plain_body text NOT NULL,
+    html_body text NOT NULL DEFAULT '',
+    headers jsonb NOT NULL DEFAULT '{}',
+    status text NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'sent', 'dead')),
+    attempts int NOT NULL DEFAULT 0,
+    last_error text NOT NULL DEFAULT '',
+    next_attempt_at timestamptz NOT NULL DEFAULT NOW(),
+    sent_at timestamptz,
+    created_at timestamptz NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS email_outbox_pending_idx ON email_outbox (next_attempt_at) WHERE status = 'pending';
+
+CREATE INDEX IF NOT EXISTS email_outbox_finished_idx ON email_outbox (created_at) WHERE status <> 'pending';
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS tokens_expires_at_idx;
@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS tokens_expires_at_idx ON tokens (expires_at);
@@ -0,0 +1,259 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// lockKey identifies the advisory lock held while migrating so that several
+// instances started with -auto-migrate apply each migration only once.
+const lockKey int64 = 7_204_118_633
+
+var (
+	ErrDirty             = errors.New("schema_migrations has a dirty version; fix the schema by hand and clear the flag")
+	ErrNoMigrationsTable = errors.New("no migrations table; run migrate up to create it")
+)
+
+// Status describes one migration and whether it has been applied. AppliedAt
+// is nil for migrations recorded without a time.
+type Status struct {
+	Migration
+	Applied   bool
+	AppliedAt *time.Time
+}
+
+type Migrator struct {
+	DB         *sql.DB
+	Logger     *slog.Logger
+	Migrations []Migration
+}
+
+// New returns a Migrator for the embedded migrations.
+func New(db *sql.DB, logger *slog.Logger) (*Migrator, error) {
+	migrations, err := Load()
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{DB: db, Logger: logger, Migrations: migrations}, nil
+}
+
+// Up applies every pending migration in order and returns how many ran.
+func (m *Migrator) Up(ctx context.Context) (int, error) {
+	applied := 0
+	err := m.locked(ctx, func(conn *sql.Conn, versions map[int64]time.Time) error {
+		for _, migration := range m.Migrations {
+			if _, ok := versions[migration.Version]; ok {
+				continue
+			}
+			m.Logger.Info("applying migration", "version", migration.Version, "name", migration.Name)
+			err := apply(ctx, conn, migration.Up,
+				`INSERT INTO schema_migrations (version) VALUES ($1)`, migration.Version)
+			if err != nil {
+				return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+			applied++
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down reverts the latest steps applied migrations and returns how many ran.
+func (m *Migrator) Down(ctx context.Context, steps int) (int, error) {
+	reverted := 0
+	err := m.locked(ctx, func(conn *sql.Conn, versions map[int64]time.Time) error {
+		for i := len(m.Migrations) - 1; i >= 0 && reverted < steps; i-- {
+			migration := m.Migrations[i]
+			if _, ok := versions[migration.Version]; !ok {
+				continue
+			}
+			if migration.Down == "" {
+				return fmt.Errorf("migration %d_%s has no down script", migration.Version, migration.Name)
+			}
+			m.Logger.Info("reverting migration", "version", migration.Version, "name", migration.Name)
+			err := apply(ctx, conn, migration.Down,
+				`DELETE FROM schema_migrations WHERE version = $1`, migration.Version)
+			if err != nil {
+				return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+			reverted++
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// Status lists every known migration along with when it was applied. It only
+// reads schema_migrations, so it neither waits for a running migration nor
+// creates or converts the table.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	var exists, hasAppliedAt bool
+	query := `
+		SELECT to_regclass('schema_migrations') IS NOT NULL,
+			EXISTS (SELECT 1 FROM information_schema.columns
+				WHERE table_schema = current_schema() AND table_name = 'schema_migrations' AND column_name = 'applied_at')`
+	err := m.DB.QueryRowContext(ctx, query).Scan(&exists, &hasAppliedAt)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrNoMigrationsTable
+	}
+
+	var dirty bool
+	var latest sql.NullInt64
+	err = m.DB.QueryRowContext(ctx, `SELECT COALESCE(bool_or(dirty), false), MAX(version) FROM schema_migrations`).Scan(&dirty, &latest)
+	if err != nil {
+		return nil, err
+	}
+	if dirty {
+		return nil, ErrDirty
+	}
+
+	versions := make(map[int64]time.Time)
+	if hasAppliedAt {
+		rows, err := m.DB.QueryContext(ctx, `SELECT version, applied_at FROM schema_migrations`)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var version int64
+			var appliedAt time.Time
+			if err := rows.Scan(&version, &appliedAt); err != nil {
+				return nil, err
+			}
+			versions[version] = appliedAt
+		}
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	var statuses []Status
+	for _, migration := range m.Migrations {
+		status := Status{Migration: migration}
+		if appliedAt, ok := versions[migration.Version]; ok {
+			status.Applied = true
+			status.AppliedAt = &appliedAt
+		} else if !hasAppliedAt && latest.Valid && migration.Version <= latest.Int64 {
+			// golang-migrate keeps only the latest version, without a time,
+			// until prepare converts its table.
+			status.Applied = true
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// locked runs fn on a single connection holding the migration advisory lock,
+// passing it the applied versions.
+func (m *Migrator) locked(ctx context.Context, fn func(*sql.Conn, map[int64]time.Time) error) error {
+	conn, err := m.DB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, lockKey)
+	if err != nil {
+		return err
+	}
+	defer conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, lockKey)
+
+	err = m.prepare(ctx, conn)
+	if err != nil {
+		return err
+	}
+	versions, err := appliedVersions(ctx, conn)
+	if err != nil {
+		return err
+	}
+	return fn(conn, versions)
+}
+
+// prepare creates schema_migrations. A table left behind by golang-migrate
+// holds a single row with the current version, so the versions below it are
+// backfilled to keep Down and Status accurate.
+func (m *Migrator) prepare(ctx context.Context, conn *sql.Conn) error {
+	query := `
+		CREATE TABLE IF NOT EXISTS schema_migrations(
+			version bigint PRIMARY KEY,
+			dirty boolean NOT NULL DEFAULT false
+		);
+		ALTER TABLE schema_migrations ALTER COLUMN dirty SET DEFAULT false;
+		ALTER TABLE schema_migrations ADD COLUMN IF NOT EXISTS applied_at timestamptz NOT NULL DEFAULT NOW();`
+	_, err := conn.ExecContext(ctx, query)
+	if err != nil {
+		return err
+	}
+
+	var dirty bool
+	err = conn.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE dirty)`).Scan(&dirty)
+	if err != nil {
+		return err
+	}
+	if dirty {
+		return ErrDirty
+	}
+
+	var latest sql.NullInt64
+	err = conn.QueryRowContext(ctx, `SELECT MAX(version) FROM schema_migrations`).Scan(&latest)
+	if err != nil {
+		return err
+	}
+	for _, migration := range m.Migrations {
+		if !latest.Valid || migration.Version >= latest.Int64 {
+			break
+		}
+		_, err = conn.ExecContext(ctx,
+			`INSERT INTO schema_migrations (version) VALUES ($1) ON CONFLICT DO NOTHING`, migration.Version)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func appliedVersions(ctx context.Context, conn *sql.Conn) (map[int64]time.Time, error) {
+	rows, err := conn.QueryContext(ctx, `SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	versions := make(map[int64]time.Time)
+	for rows.Next() {
+		var version int64
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		versions[version] = appliedAt
+	}
+	return versions, rows.Err()
+}
+
+// apply runs script and the bookkeeping statement in one transaction, so a
+// failed migration leaves neither a half-applied schema nor a recorded version.
+func apply(ctx context.Context, conn *sql.Conn, script, record string, version int64) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, script)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, record, version)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,130 @@
+// Package migrations embeds the SQL schema migrations and applies them,
+// recording each applied version in the schema_migrations table.
+package migrations
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//go:embed *.sql
+var files embed.FS
+
+// Migration is a pair of up and down scripts sharing a version number.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Load parses the embedded files named <version>_<name>.<up|down>.sql and
+// returns the migrations ordered by version.
+func Load() ([]Migration, error) {
+	return load(files)
+}
+
+func load(fsys fs.FS) ([]Migration, error) {
+	names, err := fs.Glob(fsys, "*.sql")
+	if err != nil {
+		return nil, err
+	}
+	byVersion := make(map[int64]*Migration)
+	hasUp := make(map[int64]bool)
+	for _, name := range names {
+		version, title, direction, err := parseName(name)
+		if err != nil {
+			return nil, err
+		}
+		body, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: title}
+			byVersion[version] = m
+		}
+		if m.Name != title {
+			return nil, fmt.Errorf("migration %d has two names: %q and %q", version, m.Name, title)
+		}
+		switch direction {
+		case "up":
+			m.Up = string(body)
+			hasUp[version] = true
+		case "down":
+			m.Down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if !hasUp[m.Version] {
+			return nil, fmt.Errorf("migration %d_%s has no up script", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+func parseName(name string) (version int64, title, direction string, err error) {
+	base := strings.TrimSuffix(name, ".sql")
+	base, direction, ok := cut(base, ".")
+	if !ok || (direction != "up" && direction != "down") {
+		return 0, "", "", fmt.Errorf("migration %s: name must end in .up.sql or .down.sql", name)
+	}
+	number, title, ok := strings.Cut(base, "_")
+	if !ok {
+		return 0, "", "", fmt.Errorf("migration %s: name must start with <version>_", name)
+	}
+	version, err = strconv.ParseInt(number, 10, 64)
+	if err != nil || version <= 0 {
+		return 0, "", "", fmt.Errorf("migration %s: invalid version %q", name, number)
+	}
+	return version, title, direction, nil
+}
+
+// cut slices s around the last instance of sep.
+func cut(s, sep string) (before, after string, found bool) {
+	if i := strings.LastIndex(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
+
+// Create writes empty up and down files for the next version into dir, which
+// should be the source migrations directory so the files get embedded on the
+// next build. It returns the paths of the new files.
+func Create(dir, name string) ([]string, error) {
+	name = strings.ToLower(strings.Join(strings.Fields(name), "_"))
+	if name == "" || strings.ContainsAny(name, `./\`) {
+		return nil, fmt.Errorf("invalid migration name %q", name)
+	}
+	migrations, err := load(os.DirFS(dir))
+	if err != nil {
+		return nil, err
+	}
+	var version int64 = 1
+	if len(migrations) > 0 {
+		version = migrations[len(migrations)-1].Version + 1
+	}
+
+	var paths []string
+	for _, direction := range []string{"up", "down"} {
+		path := filepath.Join(dir, fmt.Sprintf("%06d_%s.%s.sql", version, name, direction))
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if err != nil {
+			return paths, err
+		}
+		f.Close()
+		paths = append(paths, path)
+	}
+	return paths, nil
+}